@@ -0,0 +1,53 @@
+// Command toml-test-decoder reads a TOML document from standard input and
+// writes it to standard output in the tagged JSON format of toml-test.
+// It exits with status 1 if the document is invalid.
+//
+// It is the decoder to test with toml-test:
+//
+//	toml-test toml-test-decoder
+//
+// See https://github.com/toml-lang/toml-test.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	toml "github.com/Code-Hex/go-toml"
+	"github.com/Code-Hex/go-toml/internal/tag"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 0 {
+		usage()
+	}
+
+	var v interface{}
+	if err := toml.NewDecoder(os.Stdin).Decode(&v); err != nil {
+		fatalf("%+v", err)
+	}
+	tagged, err := tag.Add(v)
+	if err != nil {
+		fatalf("%s", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tagged); err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s < input.toml\n", filepath.Base(os.Args[0]))
+	os.Exit(2)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,51 @@
+// Command toml-test-encoder reads a document in the tagged JSON format of
+// toml-test from standard input and writes it to standard output as TOML.
+// It exits with status 1 if the document can not be encoded.
+//
+// It is the encoder to test with toml-test:
+//
+//	toml-test -encoder toml-test-encoder
+//
+// See https://github.com/toml-lang/toml-test.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	toml "github.com/Code-Hex/go-toml"
+	"github.com/Code-Hex/go-toml/internal/tag"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 0 {
+		usage()
+	}
+
+	var tagged interface{}
+	if err := json.NewDecoder(os.Stdin).Decode(&tagged); err != nil {
+		fatalf("%s", err)
+	}
+	v, err := tag.Remove(tagged)
+	if err != nil {
+		fatalf("%s", err)
+	}
+	if err := toml.NewEncoder(os.Stdout).Encode(v); err != nil {
+		fatalf("%s", err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s < input.json\n", filepath.Base(os.Args[0]))
+	os.Exit(2)
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
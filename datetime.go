@@ -0,0 +1,230 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A LocalDate is a TOML local date, a date without a time or a time zone.
+type LocalDate struct {
+	Year  int
+	Month time.Month
+	Day   int
+}
+
+// LocalDateOf returns the date of t in its location.
+func LocalDateOf(t time.Time) LocalDate {
+	y, m, d := t.Date()
+	return LocalDate{Year: y, Month: m, Day: d}
+}
+
+// String returns d in the TOML format, such as 1979-05-27.
+func (d LocalDate) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+// MarshalText implements encoding.TextMarshaler. The text is d.String().
+func (d LocalDate) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text must be a
+// TOML local date.
+func (d *LocalDate) UnmarshalText(text []byte) error {
+	t, err := time.Parse(layoutLocalDate, string(text))
+	if err != nil {
+		return fmt.Errorf("invalid local date %q", text)
+	}
+	*d = LocalDateOf(t)
+	return nil
+}
+
+// In returns the time at midnight of d in loc.
+func (d LocalDate) In(loc *time.Location) time.Time {
+	return time.Date(d.Year, d.Month, d.Day, 0, 0, 0, 0, loc)
+}
+
+// Compare returns -1, 0 or +1 if d is before, the same as or after d2.
+func (d LocalDate) Compare(d2 LocalDate) int {
+	return compareInts(d.Year, d2.Year, int(d.Month), int(d2.Month), d.Day, d2.Day)
+}
+
+// Before reports whether d is before d2.
+func (d LocalDate) Before(d2 LocalDate) bool {
+	return d.Compare(d2) < 0
+}
+
+// After reports whether d is after d2.
+func (d LocalDate) After(d2 LocalDate) bool {
+	return d.Compare(d2) > 0
+}
+
+// A LocalTime is a TOML local time, a time of day without a date or a time
+// zone.
+type LocalTime struct {
+	Hour       int
+	Minute     int
+	Second     int
+	Nanosecond int
+}
+
+// LocalTimeOf returns the time of day of t in its location.
+func LocalTimeOf(t time.Time) LocalTime {
+	h, m, s := t.Clock()
+	return LocalTime{Hour: h, Minute: m, Second: s, Nanosecond: t.Nanosecond()}
+}
+
+// String returns t in the TOML format, such as 07:32:00 or 00:32:00.999999.
+// The fraction of a second is written only if it is not zero.
+func (t LocalTime) String() string {
+	s := fmt.Sprintf("%02d:%02d:%02d", t.Hour, t.Minute, t.Second)
+	if t.Nanosecond == 0 {
+		return s
+	}
+	return s + strings.TrimRight(fmt.Sprintf(".%09d", t.Nanosecond), "0")
+}
+
+// MarshalText implements encoding.TextMarshaler. The text is t.String().
+func (t LocalTime) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text must be a
+// TOML local time. Digits of the fraction of a second beyond nanoseconds
+// are truncated.
+func (t *LocalTime) UnmarshalText(text []byte) error {
+	v, err := time.Parse(layoutLocalTime, string(text))
+	if err != nil || !isLocalTimeText(string(text)) {
+		return fmt.Errorf("invalid local time %q", text)
+	}
+	*t = LocalTimeOf(v)
+	return nil
+}
+
+// In returns the time t in loc on January 1 of year 0, the date
+// DateTimeNode uses for a local time.
+func (t LocalTime) In(loc *time.Location) time.Time {
+	return time.Date(0, time.January, 1, t.Hour, t.Minute, t.Second, t.Nanosecond, loc)
+}
+
+// Compare returns -1, 0 or +1 if t is before, the same as or after t2.
+func (t LocalTime) Compare(t2 LocalTime) int {
+	return compareInts(t.Hour, t2.Hour, t.Minute, t2.Minute, t.Second, t2.Second, t.Nanosecond, t2.Nanosecond)
+}
+
+// Before reports whether t is before t2.
+func (t LocalTime) Before(t2 LocalTime) bool {
+	return t.Compare(t2) < 0
+}
+
+// After reports whether t is after t2.
+func (t LocalTime) After(t2 LocalTime) bool {
+	return t.Compare(t2) > 0
+}
+
+// A LocalDateTime is a TOML local date-time, a date and a time of day
+// without a time zone.
+type LocalDateTime struct {
+	Date LocalDate
+	Time LocalTime
+}
+
+// LocalDateTimeOf returns the date and time of day of t in its location.
+func LocalDateTimeOf(t time.Time) LocalDateTime {
+	return LocalDateTime{Date: LocalDateOf(t), Time: LocalTimeOf(t)}
+}
+
+// String returns dt in the TOML format, such as 1979-05-27T07:32:00.
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + "T" + dt.Time.String()
+}
+
+// MarshalText implements encoding.TextMarshaler. The text is dt.String().
+func (dt LocalDateTime) MarshalText() ([]byte, error) {
+	return []byte(dt.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. The text must be a
+// TOML local date-time, the date and the time may be separated by a space.
+func (dt *LocalDateTime) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(string(text))
+	if len(s) > 10 && s[10] == ' ' {
+		s = s[:10] + "T" + s[11:]
+	}
+	t, err := time.Parse(layoutLocalDate+"T"+layoutLocalTime, s)
+	if err != nil || !isLocalTimeText(s[11:]) {
+		return fmt.Errorf("invalid local date-time %q", text)
+	}
+	*dt = LocalDateTimeOf(t)
+	return nil
+}
+
+// In returns the time dt in loc.
+func (dt LocalDateTime) In(loc *time.Location) time.Time {
+	d, t := dt.Date, dt.Time
+	return time.Date(d.Year, d.Month, d.Day, t.Hour, t.Minute, t.Second, t.Nanosecond, loc)
+}
+
+// Compare returns -1, 0 or +1 if dt is before, the same as or after dt2.
+func (dt LocalDateTime) Compare(dt2 LocalDateTime) int {
+	if c := dt.Date.Compare(dt2.Date); c != 0 {
+		return c
+	}
+	return dt.Time.Compare(dt2.Time)
+}
+
+// Before reports whether dt is before dt2.
+func (dt LocalDateTime) Before(dt2 LocalDateTime) bool {
+	return dt.Compare(dt2) < 0
+}
+
+// After reports whether dt is after dt2.
+func (dt LocalDateTime) After(dt2 LocalDateTime) bool {
+	return dt.Compare(dt2) > 0
+}
+
+// isLocalTimeText reports whether s has the layout of a TOML local time,
+// which time.Parse does not check: an hour of two digits and a fraction of
+// a second after a decimal point.
+func isLocalTimeText(s string) bool {
+	return len(s) >= 8 && s[2] == ':' && s[5] == ':' && (len(s) == 8 || s[8] == '.')
+}
+
+// compareInts compares the pairs of integers in xs in order, and returns
+// -1 or +1 for the first pair that differs, or 0 if none does.
+func compareInts(xs ...int) int {
+	for i := 0; i+1 < len(xs); i += 2 {
+		switch {
+		case xs[i] < xs[i+1]:
+			return -1
+		case xs[i] > xs[i+1]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// localValue returns the value of a local date-time, local date or local
+// time node as a LocalDateTime, LocalDate or LocalTime, and the value of an
+// offset date-time node as a time.Time.
+func localValue(n *DateTimeNode) interface{} {
+	switch n.Kind {
+	case KindLocalDateTime:
+		return LocalDateTimeOf(n.Value)
+	case KindLocalDate:
+		return LocalDateOf(n.Value)
+	case KindLocalTime:
+		return LocalTimeOf(n.Value)
+	}
+	return n.Value
+}
+
+// timeIn returns the value of a date-time node as a time.Time, with the
+// local forms in loc.
+func timeIn(n *DateTimeNode, loc *time.Location) time.Time {
+	if n.Kind == KindOffsetDateTime {
+		return n.Value
+	}
+	return LocalDateTimeOf(n.Value).In(loc)
+}
@@ -0,0 +1,93 @@
+package toml
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocalText(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		v    interface {
+			String() string
+			UnmarshalText([]byte) error
+		}
+		want string
+	}{
+		{"1979-05-27", &LocalDate{}, "1979-05-27"},
+		{"0001-01-01", &LocalDate{}, "0001-01-01"},
+		{"07:32:00", &LocalTime{}, "07:32:00"},
+		{"00:32:00.999999", &LocalTime{}, "00:32:00.999999"},
+		{"00:32:00.1234567891", &LocalTime{}, "00:32:00.123456789"},
+		{"23:59:59.500", &LocalTime{}, "23:59:59.5"},
+		{"1979-05-27T07:32:00", &LocalDateTime{}, "1979-05-27T07:32:00"},
+		{"1979-05-27 00:32:00.25", &LocalDateTime{}, "1979-05-27T00:32:00.25"},
+		{"1979-05-27t07:32:00", &LocalDateTime{}, "1979-05-27T07:32:00"},
+	} {
+		if err := test.v.UnmarshalText([]byte(test.text)); err != nil {
+			t.Errorf("%s: %v", test.text, err)
+			continue
+		}
+		if got := test.v.String(); got != test.want {
+			t.Errorf("%s: got %s, expected %s", test.text, got, test.want)
+		}
+	}
+}
+
+func TestLocalTextError(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		v    interface{ UnmarshalText([]byte) error }
+		want string
+	}{
+		{"1979-02-30", &LocalDate{}, `invalid local date "1979-02-30"`},
+		{"1979-05-27T07:32:00", &LocalDate{}, `invalid local date "1979-05-27T07:32:00"`},
+		{"07:32", &LocalTime{}, `invalid local time "07:32"`},
+		{"24:00:00", &LocalTime{}, `invalid local time "24:00:00"`},
+		{"7:32:00", &LocalTime{}, `invalid local time "7:32:00"`},
+		{"07:32:00,5", &LocalTime{}, `invalid local time "07:32:00,5"`},
+		{"1979-05-27T7:32:00", &LocalDateTime{}, `invalid local date-time "1979-05-27T7:32:00"`},
+		{"1979-05-27T07:32:00Z", &LocalDateTime{}, `invalid local date-time "1979-05-27T07:32:00Z"`},
+		{"1979-05-27", &LocalDateTime{}, `invalid local date-time "1979-05-27"`},
+	} {
+		err := test.v.UnmarshalText([]byte(test.text))
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, expected %s", test.text, err, test.want)
+		}
+	}
+}
+
+func TestLocalIn(t *testing.T) {
+	loc := time.FixedZone("", -8*60*60)
+	d := LocalDate{1979, time.May, 27}
+	if got, want := d.In(loc), time.Date(1979, 5, 27, 0, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("date: got %v, expected %v", got, want)
+	}
+	tm := LocalTime{7, 32, 0, 5}
+	if got, want := tm.In(time.UTC), time.Date(0, 1, 1, 7, 32, 0, 5, time.UTC); !got.Equal(want) {
+		t.Errorf("time: got %v, expected %v", got, want)
+	}
+	dt := LocalDateTime{d, tm}
+	got := dt.In(loc)
+	if want := time.Date(1979, 5, 27, 15, 32, 0, 5, time.UTC); !got.Equal(want) {
+		t.Errorf("date-time: got %v, expected %v", got, want)
+	}
+	if LocalDateTimeOf(got) != dt {
+		t.Errorf("LocalDateTimeOf(%v) = %v, expected %v", got, LocalDateTimeOf(got), dt)
+	}
+}
+
+func TestLocalCompare(t *testing.T) {
+	d1, d2 := LocalDate{1979, time.May, 27}, LocalDate{1979, time.June, 1}
+	if !d1.Before(d2) || d1.After(d2) || !d2.After(d1) || d1.Compare(d1) != 0 {
+		t.Errorf("%v and %v compare wrong", d1, d2)
+	}
+	t1, t2 := LocalTime{7, 32, 0, 1}, LocalTime{7, 32, 0, 2}
+	if !t1.Before(t2) || t1.After(t2) || !t2.After(t1) || t2.Compare(t2) != 0 {
+		t.Errorf("%v and %v compare wrong", t1, t2)
+	}
+	dt1, dt2 := LocalDateTime{d1, t2}, LocalDateTime{d2, t1}
+	if !dt1.Before(dt2) || dt1.After(dt2) || dt2.Compare(dt1) != 1 || dt1.Compare(LocalDateTime{d1, t1}) != 1 {
+		t.Errorf("%v and %v compare wrong", dt1, dt2)
+	}
+}
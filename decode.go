@@ -0,0 +1,487 @@
+package toml
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Unmarshal parses the TOML document data and stores the result in the
+// value pointed to by v. If v is nil or not a pointer, Unmarshal returns an error.
+//
+// Tables are decoded into structs, maps with string keys and empty
+// interfaces. A key is matched with the field whose name or tag name is the
+// same, or if there is none, with the field whose name matches it
+// case-insensitively. Keys without a matching field are ignored.
+//
+// Arrays and arrays of tables are decoded into slices, arrays and empty
+// interfaces. Values are decoded into fields of a compatible kind, an integer
+// may also be decoded into a float. Integers and floats may be decoded into
+// big.Int and big.Float, an integer that does not fit in a Go integer is an
+// error. Date-times are decoded into time.Time, local date-times, local
+// dates and local times in time.Local. They are also decoded into
+// LocalDateTime, LocalDate and LocalTime, which must match their form.
+//
+// To decode into an empty interface, Unmarshal stores one of these:
+//
+//	map[string]interface{}, for tables
+//	[]map[string]interface{}, for arrays of tables
+//	[]interface{}, for arrays
+//	string, int64, float64, bool, for values
+//	time.Time, for offset date-times
+//	LocalDateTime, LocalDate, LocalTime, for local date-times, dates and times
+//
+// If a Go value implements Unmarshaler, Unmarshal calls its UnmarshalTOML
+// method with the TOML value, including tables and arrays. Otherwise, if the
+// Go value implements encoding.TextUnmarshaler and the TOML value is a
+// string, Unmarshal calls its UnmarshalText method with the string.
+func Unmarshal(data []byte, v interface{}) error {
+	doc, err := Parse("", string(data))
+	if err != nil {
+		return err
+	}
+	return decodeDocument(doc, v)
+}
+
+// Unmarshaler is the interface implemented by types that can decode a TOML
+// value of themselves. The value is what Unmarshal stores in an empty
+// interface for it, such as a map[string]interface{} for a table or an
+// int64 for an integer.
+type Unmarshaler interface {
+	UnmarshalTOML(value interface{}) error
+}
+
+// A Decoder reads and decodes a TOML document from an input stream.
+type Decoder struct {
+	r io.Reader
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r}
+}
+
+// Decode reads the TOML document from its input and stores it in the value
+// pointed to by v. A table can be extended by any later part of a document,
+// so Decode reads the input until EOF before decoding it. If the input has a
+// Name method, like *os.File, the name is used in error reports.
+//
+// See the documentation for Unmarshal for details about the conversion of
+// TOML into a Go value.
+func (dec *Decoder) Decode(v interface{}) error {
+	var sb strings.Builder
+	if _, err := io.Copy(&sb, dec.r); err != nil {
+		return err
+	}
+	var name string
+	if n, ok := dec.r.(interface{ Name() string }); ok {
+		name = n.Name()
+	}
+	doc, err := Parse(name, sb.String())
+	if err != nil {
+		return err
+	}
+	return decodeDocument(doc, v)
+}
+
+// decodeDocument stores the tables and values of doc in the value pointed to by v.
+func decodeDocument(doc *Document, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("toml: Unmarshal(%s)", describeTarget(reflect.TypeOf(v)))
+	}
+	root, err := buildTable(doc)
+	if err != nil {
+		return err
+	}
+	return decodeValue(root, rv, nil)
+}
+
+func describeTarget(t reflect.Type) string {
+	if t == nil {
+		return "nil"
+	}
+	if t.Kind() != reflect.Ptr {
+		return "non-pointer " + t.String()
+	}
+	return "nil " + t.String()
+}
+
+// decodeError returns an error for the value at the key path decoded from node n.
+func decodeError(n Node, path []string, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(format, args...)
+	if len(path) > 0 {
+		msg = formatKey(path) + ": " + msg
+	}
+	if n == nil {
+		return fmt.Errorf("toml: %s", msg)
+	}
+	return fmt.Errorf("toml: line %d: %s", n.Line(), msg)
+}
+
+// indirect walks down v allocating pointers as needed,
+// until it gets to a non-pointer.
+func indirect(v reflect.Value) reflect.Value {
+	for {
+		// Load value from interface, but only if the result will be
+		// usefully addressable.
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			return v
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+}
+
+// indirectUnmarshaler walks down v like indirect, allocating pointers as
+// needed. It returns the Unmarshaler or encoding.TextUnmarshaler it finds on
+// the way, if any.
+func indirectUnmarshaler(v reflect.Value) (Unmarshaler, encoding.TextUnmarshaler) {
+	// Start with a pointer, the methods may have pointer receivers.
+	if v.Kind() != reflect.Ptr && v.Type().Name() != "" && v.CanAddr() {
+		v = v.Addr()
+	}
+	for {
+		if v.Kind() == reflect.Interface && !v.IsNil() {
+			e := v.Elem()
+			if e.Kind() == reflect.Ptr && !e.IsNil() {
+				v = e
+				continue
+			}
+		}
+		if v.Kind() != reflect.Ptr {
+			return nil, nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		if v.Type().NumMethod() > 0 && v.CanInterface() {
+			if u, ok := v.Interface().(Unmarshaler); ok {
+				return u, nil
+			}
+			if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+				return nil, u
+			}
+		}
+		v = v.Elem()
+	}
+}
+
+// isEmptyInterface reports whether v is an interface{} that can hold any value.
+func isEmptyInterface(v reflect.Value) bool {
+	return v.Kind() == reflect.Interface && v.NumMethod() == 0
+}
+
+// decodeValue stores val, which is a Node, *table or *tableArray, in v.
+func decodeValue(val interface{}, v reflect.Value, path []string) error {
+	u, tu := indirectUnmarshaler(v)
+	if u != nil {
+		var x interface{}
+		if err := decodeValue(val, reflect.ValueOf(&x).Elem(), path); err != nil {
+			return err
+		}
+		if err := u.UnmarshalTOML(x); err != nil {
+			return decodeError(valueNode(val), path, "%v", err)
+		}
+		return nil
+	}
+	if n, ok := val.(*StringNode); ok && tu != nil {
+		if err := tu.UnmarshalText([]byte(n.Value)); err != nil {
+			return decodeError(n, path, "%v", err)
+		}
+		return nil
+	}
+	switch val := val.(type) {
+	case *table:
+		return decodeTable(val, v, path)
+	case *tableArray:
+		return decodeTableArray(val, v, path)
+	case *InlineTableNode:
+		t, err := buildInlineTable(val, path)
+		if err != nil {
+			return err
+		}
+		return decodeTable(t, v, path)
+	case *ArrayNode:
+		return decodeArray(val, v, path)
+	case Node:
+		return decodeScalar(val, v, path)
+	}
+	panic(fmt.Sprintf("toml: unexpected value %T", val))
+}
+
+// valueNode returns the node that defined val, which is a Node, *table or
+// *tableArray. It is nil for the root table.
+func valueNode(val interface{}) Node {
+	switch val := val.(type) {
+	case *table:
+		return val.node
+	case *tableArray:
+		return val.node
+	}
+	return val.(Node)
+}
+
+func decodeTable(t *table, v reflect.Value, path []string) error {
+	v = indirect(v)
+	if isEmptyInterface(v) {
+		m := make(map[string]interface{}, len(t.keys))
+		mv := reflect.ValueOf(m)
+		if err := decodeTable(t, mv, path); err != nil {
+			return err
+		}
+		v.Set(mv)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := cachedTypeFields(v.Type())
+		for _, key := range t.keys {
+			f, ok := lookupField(fields, key)
+			if !ok {
+				continue
+			}
+			fv, ok := fieldByIndex(v, f.index)
+			if !ok {
+				continue
+			}
+			if err := decodeValue(t.values[key], fv, append(path, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return decodeError(t.node, path, "cannot decode table into Go value of type %s", v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(t.keys)))
+		}
+		elemType := v.Type().Elem()
+		for _, key := range t.keys {
+			ev := reflect.New(elemType).Elem()
+			if err := decodeValue(t.values[key], ev, append(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), ev)
+		}
+		return nil
+	}
+	return decodeError(t.node, path, "cannot decode table into Go value of type %s", v.Type())
+}
+
+// fieldByIndex returns the nested field of v, allocating the pointers
+// of embedded structs. It reports false if a pointer to an unexported
+// embedded struct is nil, such a field can not be set.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func decodeTableArray(a *tableArray, v reflect.Value, path []string) error {
+	v = indirect(v)
+	if isEmptyInterface(v) {
+		sv := reflect.New(reflect.TypeOf([]map[string]interface{}(nil))).Elem()
+		if err := decodeTableArray(a, sv, path); err != nil {
+			return err
+		}
+		v.Set(sv)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), len(a.tables), len(a.tables)))
+	case reflect.Array:
+		if v.Len() != len(a.tables) {
+			return decodeError(a.node, path, "cannot decode array of %d tables into Go value of type %s", len(a.tables), v.Type())
+		}
+	default:
+		return decodeError(a.node, path, "cannot decode array of tables into Go value of type %s", v.Type())
+	}
+	for i, t := range a.tables {
+		if err := decodeValue(t, v.Index(i), path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeArray(a *ArrayNode, v reflect.Value, path []string) error {
+	v = indirect(v)
+	if isEmptyInterface(v) {
+		sv := reflect.New(reflect.TypeOf([]interface{}(nil))).Elem()
+		if err := decodeArray(a, sv, path); err != nil {
+			return err
+		}
+		v.Set(sv)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), len(a.Values), len(a.Values)))
+	case reflect.Array:
+		if v.Len() != len(a.Values) {
+			return decodeError(a, path, "cannot decode array of %d values into Go value of type %s", len(a.Values), v.Type())
+		}
+	default:
+		return decodeError(a, path, "cannot decode array into Go value of type %s", v.Type())
+	}
+	for i, n := range a.Values {
+		if err := decodeValue(n, v.Index(i), path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	localDateTimeType = reflect.TypeOf(LocalDateTime{})
+	localDateType     = reflect.TypeOf(LocalDate{})
+	localTimeType     = reflect.TypeOf(LocalTime{})
+	bigIntType        = reflect.TypeOf(big.Int{})
+	bigFloatType      = reflect.TypeOf(big.Float{})
+)
+
+// isDateTimeType reports whether t is time.Time, LocalDateTime, LocalDate or
+// LocalTime, which are structs that are not tables.
+func isDateTimeType(t reflect.Type) bool {
+	switch t {
+	case timeType, localDateTimeType, localDateType, localTimeType:
+		return true
+	}
+	return false
+}
+
+// decodeScalar stores the string, integer, float, boolean or date-time of n in v.
+func decodeScalar(n Node, v reflect.Value, path []string) error {
+	v = indirect(v)
+	if isEmptyInterface(v) {
+		v.Set(reflect.ValueOf(scalarValue(n)))
+		return nil
+	}
+	switch n := n.(type) {
+	case *StringNode:
+		if v.Kind() == reflect.String {
+			v.SetString(n.Value)
+			return nil
+		}
+	case *IntegerNode:
+		switch {
+		case v.Type() == bigIntType && v.CanAddr():
+			v.Addr().Interface().(*big.Int).SetInt64(n.Value)
+			return nil
+		case v.Type() == bigFloatType && v.CanAddr():
+			v.Addr().Interface().(*big.Float).SetInt64(n.Value)
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.OverflowInt(n.Value) {
+				return decodeError(n, path, "integer %s overflows Go value of type %s", n.Text, v.Type())
+			}
+			v.SetInt(n.Value)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if n.Value < 0 || v.OverflowUint(uint64(n.Value)) {
+				return decodeError(n, path, "integer %s overflows Go value of type %s", n.Text, v.Type())
+			}
+			v.SetUint(uint64(n.Value))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			v.SetFloat(float64(n.Value))
+			return nil
+		}
+	case *FloatNode:
+		if v.Type() == bigFloatType && v.CanAddr() && !math.IsNaN(n.Value) {
+			v.Addr().Interface().(*big.Float).SetFloat64(n.Value)
+			return nil
+		}
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if v.OverflowFloat(n.Value) {
+				return decodeError(n, path, "float %s overflows Go value of type %s", n.Text, v.Type())
+			}
+			v.SetFloat(n.Value)
+			return nil
+		}
+	case *BoolNode:
+		if v.Kind() == reflect.Bool {
+			v.SetBool(n.Value)
+			return nil
+		}
+	case *DateTimeNode:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(timeIn(n, time.Local)))
+			return nil
+		}
+		if x := reflect.ValueOf(localValue(n)); x.Type() == v.Type() {
+			v.Set(x)
+			return nil
+		}
+	}
+	return decodeError(n, path, "cannot decode %s into Go value of type %s", describeNode(n), v.Type())
+}
+
+// scalarValue returns the Go value of a string, integer, float, boolean or date-time node.
+func scalarValue(n Node) interface{} {
+	switch n := n.(type) {
+	case *StringNode:
+		return n.Value
+	case *IntegerNode:
+		return n.Value
+	case *FloatNode:
+		return n.Value
+	case *BoolNode:
+		return n.Value
+	case *DateTimeNode:
+		return localValue(n)
+	}
+	panic(fmt.Sprintf("toml: unexpected node %T", n))
+}
+
+// describeNode returns the TOML type of a value node for error messages.
+func describeNode(n Node) string {
+	switch n := n.(type) {
+	case *StringNode:
+		return "string"
+	case *IntegerNode:
+		return "integer " + n.Text
+	case *FloatNode:
+		return "float " + n.Text
+	case *BoolNode:
+		return "boolean"
+	case *DateTimeNode:
+		return n.Kind.String()
+	case *ArrayNode:
+		return "array"
+	case *InlineTableNode:
+		return "inline table"
+	}
+	return fmt.Sprintf("%T", n)
+}
@@ -0,0 +1,464 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type decodeServer struct {
+	Host    string
+	Port    uint16
+	Enabled bool `toml:"enabled"`
+	Timeout float64
+	Tags    []string `toml:"tags,omitempty"`
+	Ignored string   `toml:"-"`
+}
+
+type decodeProduct struct {
+	Name  string `toml:"name"`
+	SKU   int    `toml:"sku"`
+	Color *string
+}
+
+type decodeEmbedded struct {
+	Version int `toml:"version"`
+}
+
+type decodeConfig struct {
+	decodeEmbedded
+	Title    string                 `toml:"title"`
+	Released time.Time              `toml:"released"`
+	Server   decodeServer           `toml:"server"`
+	Backup   *decodeServer          `toml:"backup,inline"`
+	Products []decodeProduct        `toml:"products"`
+	Matrix   [2][]int               `toml:"matrix"`
+	Labels   map[string]string      `toml:"labels"`
+	Extra    map[string]interface{} `toml:"extra"`
+	Any      interface{}            `toml:"any"`
+}
+
+const decodeInput = `
+title = "TOML Example"
+version = 2
+released = 1979-05-27T07:32:00Z
+matrix = [ [1, 2], [3] ]
+any = [ 1, "two", { three = 3.0 } ]
+
+[server]
+host = "localhost"
+PORT = 8080
+enabled = true
+timeout = 5
+tags = [ "a", "b" ]
+ignored = "not decoded"
+unknown = "is ignored"
+
+[backup]
+host = "backup.example.com"
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]
+
+[[products]]
+name = "Nail"
+sku = 284758393
+color = "gray"
+
+[labels]
+env = "prod"
+
+[extra]
+nested.key = 1
+list = [ { a = 1 }, { b = true } ]
+`
+
+func TestUnmarshal(t *testing.T) {
+	var got decodeConfig
+	if err := Unmarshal([]byte(decodeInput), &got); err != nil {
+		t.Fatal(err)
+	}
+	gray := "gray"
+	want := decodeConfig{
+		decodeEmbedded: decodeEmbedded{Version: 2},
+		Title:          "TOML Example",
+		Released:       time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		Server: decodeServer{
+			Host:    "localhost",
+			Port:    8080,
+			Enabled: true,
+			Timeout: 5,
+			Tags:    []string{"a", "b"},
+		},
+		Backup: &decodeServer{Host: "backup.example.com"},
+		Products: []decodeProduct{
+			{Name: "Hammer", SKU: 738594937},
+			{},
+			{Name: "Nail", SKU: 284758393, Color: &gray},
+		},
+		Matrix: [2][]int{{1, 2}, {3}},
+		Labels: map[string]string{"env": "prod"},
+		Extra: map[string]interface{}{
+			"nested": map[string]interface{}{"key": int64(1)},
+			"list": []interface{}{
+				map[string]interface{}{"a": int64(1)},
+				map[string]interface{}{"b": true},
+			},
+		},
+		Any: []interface{}{
+			int64(1),
+			"two",
+			map[string]interface{}{"three": 3.0},
+		},
+	}
+	if !got.Released.Equal(want.Released) {
+		t.Errorf("released: got %v, expected %v", got.Released, want.Released)
+	}
+	got.Released = want.Released
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n\t%#v\nexpected\n\t%#v", got, want)
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	var got interface{}
+	input := "a = 1\n[b]\nc = 'd'\n[[e]]\nf = 1.5\n[[e]]\n"
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"a": int64(1),
+		"b": map[string]interface{}{"c": "d"},
+		"e": []map[string]interface{}{
+			{"f": 1.5},
+			{},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n\t%#v\nexpected\n\t%#v", got, want)
+	}
+}
+
+func TestUnmarshalDateTimes(t *testing.T) {
+	var got struct {
+		ODT  time.Time
+		LDT  LocalDateTime
+		LD   *LocalDate
+		LT   LocalTime
+		Time time.Time
+		Any  []interface{}
+	}
+	input := "odt = 1979-05-27T07:32:00-08:00\nldt = 1979-05-27 07:32:00.5\nld = 1979-05-27\nlt = 00:32:00\n" +
+		"time = 1979-05-27\nany = [1979-05-27T07:32:00Z, 1979-05-27T07:32:00, 1979-05-27, 07:32:00]"
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	ld := LocalDate{1979, time.May, 27}
+	ldt := LocalDateTime{ld, LocalTime{7, 32, 0, 500000000}}
+	if want := time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC); !got.ODT.Equal(want) {
+		t.Errorf("odt: got %v, expected %v", got.ODT, want)
+	}
+	if got.LDT != ldt || got.LD == nil || *got.LD != ld || got.LT != (LocalTime{0, 32, 0, 0}) {
+		t.Errorf("got %v, %v and %v, expected %v, %v and 00:32:00", got.LDT, got.LD, got.LT, ldt, ld)
+	}
+	if want := ld.In(time.Local); !got.Time.Equal(want) || got.Time.Location() != time.Local {
+		t.Errorf("time: got %v, expected %v", got.Time, want)
+	}
+	want := []interface{}{
+		time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		LocalDateTime{ld, LocalTime{7, 32, 0, 0}},
+		ld,
+		LocalTime{7, 32, 0, 0},
+	}
+	if !reflect.DeepEqual(got.Any, want) {
+		t.Errorf("any: got %#v, expected %#v", got.Any, want)
+	}
+}
+
+func TestUnmarshalDateTimesDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = loc
+
+	// 02:30 does not exist in New York on that day, the clocks moved from
+	// 02:00 to 03:00.
+	const input = "ldt = 2021-03-14T02:30:00\nany = 2021-03-14T02:30:00"
+	var got struct {
+		LDT LocalDateTime
+		Any interface{}
+	}
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := LocalDateTime{LocalDate{2021, time.March, 14}, LocalTime{2, 30, 0, 0}}
+	if got.LDT != want || got.Any != want {
+		t.Errorf("got %v and %v, expected %v", got.LDT, got.Any, want)
+	}
+	tree, err := ParseTree("", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := tree.Get("ldt"); v != want {
+		t.Errorf("tree: got %v, expected %v", v, want)
+	}
+}
+
+func TestUnmarshalNumbers(t *testing.T) {
+	var got struct {
+		I8    int8
+		U16   uint16
+		F32   float32
+		Int   *big.Int
+		Float big.Float
+		Ints  []big.Int
+	}
+	input := "i8 = -128\nu16 = 0xffff\nf32 = 1.5\nint = -9_223_372_036_854_775_808\nfloat = 2.5e-3\nints = [1, 0b11]"
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.I8 != -128 || got.U16 != 0xffff || got.F32 != 1.5 {
+		t.Errorf("got %d, %d, %v, expected -128, 65535, 1.5", got.I8, got.U16, got.F32)
+	}
+	if got.Int.String() != "-9223372036854775808" {
+		t.Errorf("int: got %v, expected -9223372036854775808", got.Int)
+	}
+	if f, _ := got.Float.Float64(); f != 2.5e-3 {
+		t.Errorf("float: got %v, expected 2.5e-3", f)
+	}
+	if len(got.Ints) != 2 || got.Ints[0].Int64() != 1 || got.Ints[1].Int64() != 3 {
+		t.Errorf("ints: got %v, expected [1 3]", got.Ints)
+	}
+}
+
+type decodeLevel int
+
+func (l *decodeLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "debug":
+		*l = 0
+	case "info":
+		*l = 1
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type decodeDuration struct {
+	time.Duration
+}
+
+func (d *decodeDuration) UnmarshalText(text []byte) error {
+	var err error
+	d.Duration, err = time.ParseDuration(string(text))
+	return err
+}
+
+// decodeAddr is a host and port, written as a table or as a port number.
+type decodeAddr struct {
+	Host string
+	Port int64
+}
+
+func (a *decodeAddr) UnmarshalTOML(value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		a.Host, a.Port = "localhost", v
+	case map[string]interface{}:
+		a.Host, _ = v["host"].(string)
+		a.Port, _ = v["port"].(int64)
+	default:
+		return fmt.Errorf("invalid address %v", value)
+	}
+	return nil
+}
+
+func TestUnmarshalUnmarshaler(t *testing.T) {
+	var got struct {
+		IP      net.IP
+		Level   decodeLevel
+		Levels  []decodeLevel
+		Timeout *decodeDuration
+		Addr    decodeAddr
+		Addrs   []*decodeAddr
+		Servers map[string]decodeAddr
+		Peers   []decodeAddr
+	}
+	input := `ip = "192.168.0.1"
+level = "info"
+levels = ["debug", "info"]
+timeout = "1m30s"
+addr = 8080
+addrs = [80, { host = "example.com", port = 443 }]
+[servers.a]
+host = "a.example.com"
+port = 1
+[[peers]]
+host = "b.example.com"
+`
+	if err := Unmarshal([]byte(input), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.IP.Equal(net.IPv4(192, 168, 0, 1)) {
+		t.Errorf("ip: got %v, expected 192.168.0.1", got.IP)
+	}
+	if got.Level != 1 || !reflect.DeepEqual(got.Levels, []decodeLevel{0, 1}) {
+		t.Errorf("level: got %v and %v, expected 1 and [0 1]", got.Level, got.Levels)
+	}
+	if got.Timeout == nil || got.Timeout.Duration != 90*time.Second {
+		t.Errorf("timeout: got %v, expected 1m30s", got.Timeout)
+	}
+	if want := (decodeAddr{"localhost", 8080}); got.Addr != want {
+		t.Errorf("addr: got %v, expected %v", got.Addr, want)
+	}
+	if len(got.Addrs) != 2 || *got.Addrs[0] != (decodeAddr{"localhost", 80}) || *got.Addrs[1] != (decodeAddr{"example.com", 443}) {
+		t.Errorf("addrs: got %v, expected [localhost:80 example.com:443]", got.Addrs)
+	}
+	if want := map[string]decodeAddr{"a": {"a.example.com", 1}}; !reflect.DeepEqual(got.Servers, want) {
+		t.Errorf("servers: got %v, expected %v", got.Servers, want)
+	}
+	if want := []decodeAddr{{"b.example.com", 0}}; !reflect.DeepEqual(got.Peers, want) {
+		t.Errorf("peers: got %v, expected %v", got.Peers, want)
+	}
+}
+
+func TestUnmarshalUnmarshalerError(t *testing.T) {
+	type config struct {
+		Level decodeLevel
+		Addr  decodeAddr
+		IP    net.IP
+	}
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"\nlevel = 'trace'", `toml: line 2: level: unknown level "trace"`},
+		{"level = true", "toml: line 1: level: cannot decode boolean into Go value of type toml.decodeLevel"},
+		{"addr = 'x'", "toml: line 1: addr: invalid address x"},
+		{"ip = '1.2.3'", "toml: line 1: ip: invalid IP address: 1.2.3"},
+	} {
+		var c config
+		err := Unmarshal([]byte(test.input), &c)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%q: got error\n\t%v\nexpected\n\t%s", test.input, err, test.want)
+		}
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	type config struct {
+		Name  string
+		Port  uint8
+		Ports [2]int
+		Sub   struct{ Value bool }
+		Table map[int]string
+		Small struct {
+			I8  int8
+			U16 uint16
+			F   big.Float
+		}
+		Date LocalDate
+	}
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"name = 1", "toml: line 1: name: cannot decode integer 1 into Go value of type string"},
+		{"\nport = 256", "toml: line 2: port: integer 256 overflows Go value of type uint8"},
+		{"\n\nport = -1", "toml: line 3: port: integer -1 overflows Go value of type uint8"},
+		{"ports = [1, 2, 3]", "toml: line 1: ports: cannot decode array of 3 values into Go value of type [2]int"},
+		{"ports = [1, 'x']", "toml: line 1: ports: cannot decode string into Go value of type int"},
+		{"[sub]\nvalue = 1979-05-27", "toml: line 2: sub.value: cannot decode local date into Go value of type bool"},
+		{"[table]\nx = 'y'", "toml: line 1: table: cannot decode table into Go value of type map[int]string"},
+		{"name.x = 1", "toml: line 1: name: cannot decode table into Go value of type string"},
+		{"small.i8 = 128", "toml: line 1: small.i8: integer 128 overflows Go value of type int8"},
+		{"small.i8 = -129", "toml: line 1: small.i8: integer -129 overflows Go value of type int8"},
+		{"[small]\nu16 = 65_536", "toml: line 2: small.u16: integer 65_536 overflows Go value of type uint16"},
+		{"[small]\nf = nan", "toml: line 2: small.f: cannot decode float nan into Go value of type big.Float"},
+		{"date = 07:32:00", "toml: line 1: date: cannot decode local time into Go value of type toml.LocalDate"},
+		{"date = 1979-05-27T07:32:00", "toml: line 1: date: cannot decode local date-time into Go value of type toml.LocalDate"},
+		{"date = 'May 27'", `toml: line 1: date: invalid local date "May 27"`},
+		{"a = 1\na.b = 2", "toml: line 2, column 1: key a is already defined as a value at line 1, column 1"},
+		{"name = ", "toml: line 1, column 8: invalid unspecified value"},
+	} {
+		var c config
+		err := Unmarshal([]byte(test.input), &c)
+		if err == nil {
+			t.Errorf("%q: expected error", test.input)
+			continue
+		}
+		if got := err.Error(); got != test.want {
+			t.Errorf("%q: got error\n\t%s\nexpected\n\t%s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestUnmarshalInvalidTarget(t *testing.T) {
+	var m map[string]interface{}
+	for _, test := range []struct {
+		v    interface{}
+		want string
+	}{
+		{nil, "toml: Unmarshal(nil)"},
+		{m, "toml: Unmarshal(non-pointer map[string]interface {})"},
+		{(*int)(nil), "toml: Unmarshal(nil *int)"},
+	} {
+		err := Unmarshal([]byte("a = 1"), test.v)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%T: got error %v, expected %s", test.v, err, test.want)
+		}
+	}
+}
+
+type namedReader struct {
+	*strings.Reader
+}
+
+func (namedReader) Name() string { return "config.toml" }
+
+func TestDecoder(t *testing.T) {
+	type config struct {
+		Server struct {
+			Ports []int `toml:"ports"`
+		} `toml:"server"`
+	}
+	const input = "[server]\nports = [ 8000, 8001 ]\n"
+	for _, r := range []io.Reader{
+		strings.NewReader(input),
+		iotest.OneByteReader(strings.NewReader(input)),
+		iotest.HalfReader(strings.NewReader(input)),
+	} {
+		var c config
+		if err := NewDecoder(r).Decode(&c); err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{8000, 8001}; !reflect.DeepEqual(c.Server.Ports, want) {
+			t.Errorf("got %v, expected %v", c.Server.Ports, want)
+		}
+	}
+}
+
+func TestDecoderError(t *testing.T) {
+	var v interface{}
+	errRead := errors.New("read error")
+	if err := NewDecoder(iotest.ErrReader(errRead)).Decode(&v); err != errRead {
+		t.Errorf("got error %v, expected %v", err, errRead)
+	}
+	err := NewDecoder(namedReader{strings.NewReader("a = 1\nb =")}).Decode(&v)
+	if want := "toml: config.toml:2:4: invalid unspecified value"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+}
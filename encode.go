@@ -0,0 +1,330 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal returns the TOML encoding of v.
+//
+// v must be a struct or a map with string keys, possibly behind pointers
+// or interfaces. Its keys are written before its tables, so that they
+// belong to the root table. Nested structs and maps are written as [table]
+// sections and slices of structs or maps as [[array of tables]] sections.
+// A struct field tagged with the "inline" option is written as an inline
+// table instead, and a field tagged with "omitempty" is omitted if it has
+// an empty value. Nil pointers and interfaces are omitted, TOML has no
+// null value. A time.Time is written as an offset date-time, and a
+// LocalDateTime, LocalDate or LocalTime without an offset.
+//
+// Map keys are sorted. Keys that can not be written as bare keys are quoted.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// An Encoder writes TOML documents to an output stream.
+type Encoder struct {
+	w io.Writer
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// Encode writes the TOML encoding of v to the stream.
+//
+// See the documentation for Marshal for details about the
+// conversion of Go values to TOML.
+func (enc *Encoder) Encode(v interface{}) error {
+	e := &encodeState{}
+	rv := indirectValue(reflect.ValueOf(v))
+	if !isTable(rv) {
+		return fmt.Errorf("toml: cannot encode %s as a document, it must be a struct or a map", describeValue(rv))
+	}
+	if err := e.table(nil, rv, false); err != nil {
+		return err
+	}
+	_, err := enc.w.Write(e.Bytes())
+	return err
+}
+
+// encodeState encodes a document into a bytes.Buffer.
+type encodeState struct {
+	bytes.Buffer
+}
+
+// entry is a key and its value in a table to encode.
+type entry struct {
+	key    string
+	value  reflect.Value
+	inline bool
+}
+
+// indirectValue walks down v through pointers and interfaces.
+// It returns the zero Value if it reaches a nil one.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func describeValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	return "Go value of type " + v.Type().String()
+}
+
+// isTable reports whether v is encoded as a table.
+func isTable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map:
+		return true
+	case reflect.Struct:
+		return !isDateTimeType(v.Type())
+	}
+	return false
+}
+
+// isTableArray reports whether v is encoded as an array of tables.
+func isTableArray(v reflect.Value) bool {
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Len() == 0 {
+		return false
+	}
+	for i := 0; i < v.Len(); i++ {
+		if !isTable(indirectValue(v.Index(i))) {
+			return false
+		}
+	}
+	return true
+}
+
+// entries returns the keys and values of the struct or map v in the order
+// they are encoded. Nil values and empty values of fields tagged with
+// omitempty are left out.
+func entries(v reflect.Value) ([]entry, error) {
+	var es []entry
+	if v.Kind() == reflect.Map {
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("toml: cannot encode map with key type %s, keys must be strings", v.Type().Key())
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			ev := indirectValue(v.MapIndex(k))
+			if !ev.IsValid() {
+				continue
+			}
+			es = append(es, entry{key: k.String(), value: ev})
+		}
+		return es, nil
+	}
+	for _, f := range cachedTypeFields(v.Type()) {
+		fv, ok := fieldByIndexNoAlloc(v, f.index)
+		if !ok || f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		fv = indirectValue(fv)
+		if !fv.IsValid() {
+			continue
+		}
+		es = append(es, entry{key: f.name, value: fv, inline: f.inline})
+	}
+	return es, nil
+}
+
+// fieldByIndexNoAlloc returns the nested field of v. It reports false if
+// it passes through a nil pointer of an embedded struct.
+func fieldByIndexNoAlloc(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// table writes the table v at the key path. The keys of the table are
+// written first, followed by its tables and arrays of tables. The header
+// is written only if the table has keys of its own or nothing else,
+// a header for a table that only holds tables is implied by theirs.
+func (e *encodeState) table(path []string, v reflect.Value, arrayElem bool) error {
+	es, err := entries(v)
+	if err != nil {
+		return err
+	}
+	var values, tables []entry
+	for _, en := range es {
+		if !en.inline && (isTable(en.value) || isTableArray(en.value)) {
+			tables = append(tables, en)
+			continue
+		}
+		values = append(values, en)
+	}
+	if arrayElem {
+		e.header("[[", path, "]]")
+	} else if len(path) > 0 && (len(values) > 0 || len(tables) == 0) {
+		e.header("[", path, "]")
+	}
+	for _, en := range values {
+		e.WriteString(quoteKey(en.key))
+		e.WriteString(" = ")
+		if err := e.value(append(path, en.key), en.value); err != nil {
+			return err
+		}
+		e.WriteByte('\n')
+	}
+	for _, en := range tables {
+		sub := append(path[:len(path):len(path)], en.key)
+		if isTable(en.value) {
+			if err := e.table(sub, en.value, false); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := 0; i < en.value.Len(); i++ {
+			if err := e.table(sub, indirectValue(en.value.Index(i)), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *encodeState) header(open string, path []string, close string) {
+	if e.Len() > 0 {
+		e.WriteByte('\n')
+	}
+	e.WriteString(open)
+	e.WriteString(formatKey(path))
+	e.WriteString(close)
+	e.WriteByte('\n')
+}
+
+// value writes v as a value of the key path.
+func (e *encodeState) value(path []string, v reflect.Value) error {
+	v = indirectValue(v)
+	if !v.IsValid() {
+		return fmt.Errorf("toml: %s: cannot encode nil", formatKey(path))
+	}
+	switch v.Type() {
+	case timeType:
+		e.WriteString(v.Interface().(time.Time).Format(time.RFC3339Nano))
+		return nil
+	case localDateTimeType, localDateType, localTimeType:
+		e.WriteString(v.Interface().(fmt.Stringer).String())
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		e.WriteString(quoteString(v.String()))
+	case reflect.Bool:
+		e.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt64 {
+			return fmt.Errorf("toml: %s: integer %d overflows a TOML integer", formatKey(path), u)
+		}
+		e.WriteString(strconv.FormatUint(u, 10))
+	case reflect.Float32, reflect.Float64:
+		e.WriteString(formatFloat(v.Float(), v.Type().Bits()))
+	case reflect.Slice, reflect.Array:
+		e.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				e.WriteString(", ")
+			}
+			if err := e.value(path, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		e.WriteByte(']')
+	case reflect.Map, reflect.Struct:
+		return e.inlineTable(path, v)
+	default:
+		return fmt.Errorf("toml: %s: cannot encode %s", formatKey(path), describeValue(v))
+	}
+	return nil
+}
+
+func (e *encodeState) inlineTable(path []string, v reflect.Value) error {
+	es, err := entries(v)
+	if err != nil {
+		return err
+	}
+	if len(es) == 0 {
+		e.WriteString("{}")
+		return nil
+	}
+	e.WriteString("{ ")
+	for i, en := range es {
+		if i > 0 {
+			e.WriteString(", ")
+		}
+		e.WriteString(quoteKey(en.key))
+		e.WriteString(" = ")
+		if err := e.value(append(path, en.key), en.value); err != nil {
+			return err
+		}
+	}
+	e.WriteString(" }")
+	return nil
+}
+
+// formatFloat formats f as a TOML float, which always has a decimal point
+// or an exponent.
+func formatFloat(f float64, bits int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	s := strconv.FormatFloat(f, 'g', -1, bits)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
@@ -0,0 +1,179 @@
+package toml
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type encodeOwner struct {
+	Name string    `toml:"name"`
+	DOB  time.Time `toml:"dob"`
+}
+
+type encodePoint struct {
+	X, Y int
+}
+
+type encodeProduct struct {
+	Name  string  `toml:"name"`
+	SKU   int     `toml:"sku,omitempty"`
+	Color *string `toml:"color"`
+}
+
+type encodeConfig struct {
+	Title    string                       `toml:"title"`
+	Owner    encodeOwner                  `toml:"owner"`
+	Servers  map[string]map[string]string `toml:"servers"`
+	Products []encodeProduct              `toml:"products"`
+	Ports    []uint16                     `toml:"ports"`
+	Ratio    float64                      `toml:"ratio"`
+	Point    encodePoint                  `toml:"point,inline"`
+	Points   []encodePoint                `toml:"points,inline"`
+	Skipped  string                       `toml:"-"`
+	Empty    string                       `toml:"empty,omitempty"`
+	Nil      *encodeOwner                 `toml:"nil"`
+	Enabled  bool                         `toml:"enabled"`
+}
+
+func TestMarshal(t *testing.T) {
+	gray := "gray"
+	v := encodeConfig{
+		Title: "TOML Example",
+		Owner: encodeOwner{
+			Name: "Tom Preston-Werner",
+			DOB:  time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60)),
+		},
+		Servers: map[string]map[string]string{
+			"beta":         {"ip": "10.0.0.2"},
+			"alpha":        {"ip": "10.0.0.1", "role": "frontend"},
+			"a.example.io": {},
+		},
+		Products: []encodeProduct{
+			{Name: "Hammer", SKU: 738594937},
+			{Name: "Nail", Color: &gray},
+		},
+		Ports:   []uint16{8000, 8001},
+		Ratio:   2,
+		Point:   encodePoint{X: 1, Y: 2},
+		Points:  []encodePoint{{1, 2}, {3, 4}},
+		Skipped: "skipped",
+		Enabled: true,
+	}
+	got, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `title = "TOML Example"
+ports = [8000, 8001]
+ratio = 2.0
+point = { X = 1, Y = 2 }
+points = [{ X = 1, Y = 2 }, { X = 3, Y = 4 }]
+enabled = true
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00-08:00
+
+[servers."a.example.io"]
+
+[servers.alpha]
+ip = "10.0.0.1"
+role = "frontend"
+
+[servers.beta]
+ip = "10.0.0.2"
+
+[[products]]
+name = "Hammer"
+sku = 738594937
+
+[[products]]
+name = "Nail"
+color = "gray"
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nexpected\n%s", got, want)
+	}
+
+	var back encodeConfig
+	if err := Unmarshal(got, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !back.Owner.DOB.Equal(v.Owner.DOB) {
+		t.Errorf("dob: got %v, expected %v", back.Owner.DOB, v.Owner.DOB)
+	}
+	back.Owner.DOB = v.Owner.DOB
+	v.Skipped = ""
+	if !reflect.DeepEqual(back, v) {
+		t.Errorf("round trip: got\n\t%#v\nexpected\n\t%#v", back, v)
+	}
+}
+
+func TestMarshalValues(t *testing.T) {
+	for _, test := range []struct {
+		v    interface{}
+		want string
+	}{
+		{map[string]interface{}{"s": "a\"b\\c\td\u0001"}, `s = "a\"b\\c\td\u0001"` + "\n"},
+		{map[string]interface{}{"f": math.Inf(-1)}, "f = -inf\n"},
+		{map[string]interface{}{"f": math.NaN()}, "f = nan\n"},
+		{map[string]interface{}{"f": float32(0.1)}, "f = 0.1\n"},
+		{map[string]interface{}{"f": 1e21}, "f = 1e+21\n"},
+		{map[string]interface{}{"i": int8(-8)}, "i = -8\n"},
+		{map[string]interface{}{"a": []interface{}{1, "x", []int{}}}, `a = [1, "x", []]` + "\n"},
+		{map[string]interface{}{"empty table": map[string]int{}}, "[\"empty table\"]\n"},
+		{map[string]interface{}{"": 1, "ʎǝʞ": 2, "a-b_c": 3}, "\"\" = 1\na-b_c = 3\n\"ʎǝʞ\" = 2\n"},
+		{map[string]interface{}{"a": map[string]interface{}{"b": map[string]int{"c": 1}}}, "[a.b]\nc = 1\n"},
+		{&struct{ P *int }{}, ""},
+		{map[string]interface{}{"d": LocalDate{1979, 5, 27}}, "d = 1979-05-27\n"},
+		{map[string]interface{}{"t": &LocalTime{0, 32, 0, 999000}}, "t = 00:32:00.000999\n"},
+		{map[string]interface{}{"dt": []LocalDateTime{{LocalDate{1979, 5, 27}, LocalTime{7, 32, 0, 0}}}}, "dt = [1979-05-27T07:32:00]\n"},
+	} {
+		got, err := Marshal(test.v)
+		if err != nil {
+			t.Errorf("%#v: unexpected error: %v", test.v, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("%#v: got\n%s\nexpected\n%s", test.v, got, test.want)
+		}
+	}
+}
+
+func TestMarshalError(t *testing.T) {
+	for _, test := range []struct {
+		v    interface{}
+		want string
+	}{
+		{1, "toml: cannot encode Go value of type int as a document, it must be a struct or a map"},
+		{nil, "toml: cannot encode nil as a document, it must be a struct or a map"},
+		{map[int]int{1: 1}, "toml: cannot encode map with key type int, keys must be strings"},
+		{map[string]uint64{"u": math.MaxUint64}, "toml: u: integer 18446744073709551615 overflows a TOML integer"},
+		{map[string]interface{}{"a": []interface{}{nil}}, "toml: a: cannot encode nil"},
+		{map[string]interface{}{"c": make(chan int)}, "toml: c: cannot encode Go value of type chan int"},
+	} {
+		_, err := Marshal(test.v)
+		if err == nil {
+			t.Errorf("%#v: expected error", test.v)
+			continue
+		}
+		if got := err.Error(); got != test.want {
+			t.Errorf("%#v: got error\n\t%s\nexpected\n\t%s", test.v, got, test.want)
+		}
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, `a = "b"`) {
+		t.Errorf("got %q", got)
+	}
+}
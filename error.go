@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A SyntaxError is a description of a TOML syntax error.
+//
+// Printed with the %+v verb, the error is followed by a snippet of the
+// document showing the offending line with a caret under the error:
+//
+//	toml: config.toml:2:11: invalid character: `U+0021 '!'`
+//	2 | port = 80 !
+//	  |           ^
+type SyntaxError struct {
+	Name   string // name of the document, may be empty
+	Line   int    // line number, starting at 1
+	Column int    // column number in runes, starting at 1
+	Offset int    // byte offset in the document, starting at 0
+	Msg    string // description of the error
+
+	source string // the offending line without its line ending
+}
+
+// newSyntaxError returns a syntax error at byte offset off in input,
+// which is on the given line.
+func newSyntaxError(name, input string, off Pos, line int, msg string) *SyntaxError {
+	o := int(off)
+	if o > len(input) {
+		o = len(input)
+	}
+	start := lineStart(input, o)
+	end := strings.IndexByte(input[o:], '\n')
+	if end < 0 {
+		end = len(input)
+	} else {
+		end += o
+	}
+	return &SyntaxError{
+		Name:   name,
+		Line:   line,
+		Column: column(input, o),
+		Offset: o,
+		Msg:    msg,
+		source: strings.TrimSuffix(input[start:end], "\r"),
+	}
+}
+
+// lineStart returns the byte offset of the line holding offset off.
+func lineStart(input string, off int) int {
+	return strings.LastIndexByte(input[:off], '\n') + 1
+}
+
+// column returns the column in runes of byte offset off, starting at 1.
+func column(input string, off int) int {
+	if off > len(input) {
+		off = len(input)
+	}
+	return utf8.RuneCountInString(input[lineStart(input, off):off]) + 1
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("toml: line %d, column %d: %s", e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("toml: %s:%d:%d: %s", e.Name, e.Line, e.Column, e.Msg)
+}
+
+// Snippet returns the offending line of the document prefixed with its line
+// number, followed by a line with a caret under the column of the error.
+// Tabs are kept in front of the caret so that it lines up with the source.
+func (e *SyntaxError) Snippet() string {
+	num := strconv.Itoa(e.Line)
+	var b strings.Builder
+	b.WriteString(num)
+	b.WriteString(" | ")
+	b.WriteString(e.source)
+	b.WriteByte('\n')
+	b.WriteString(strings.Repeat(" ", len(num)))
+	b.WriteString(" | ")
+	col := 1
+	for _, r := range e.source {
+		if col >= e.Column {
+			break
+		}
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+		col++
+	}
+	b.WriteByte('^')
+	return b.String()
+}
+
+// Format implements fmt.Formatter. The %+v verb prints the error followed by
+// its snippet, the other verbs print it like Error.
+func (e *SyntaxError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			fmt.Fprintf(s, "%s\n%s", e.Error(), e.Snippet())
+			return
+		}
+		fmt.Fprint(s, e.Error())
+	case 's':
+		fmt.Fprint(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*toml.SyntaxError=%s)", verb, e.Error())
+	}
+}
@@ -0,0 +1,68 @@
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		input   string
+		line    int
+		column  int
+		offset  int
+		want    string
+		snippet string
+	}{
+		{
+			"config.toml", "[server]\nport = 80 !\n", 2, 11, 19,
+			"toml: config.toml:2:11: expected newline after value: `U+0021 '!'`",
+			"2 | port = 80 !\n  |           ^",
+		},
+		{
+			"", "a = 1\nb =", 2, 4, 9,
+			"toml: line 2, column 4: invalid unspecified value",
+			"2 | b =\n  |    ^",
+		},
+		{
+			"", "name = \"ʞ\\q\"\r\n", 1, 11, 11,
+			"toml: line 1, column 11: invalid escape: `U+0071 'q'`",
+			"1 | name = \"ʞ\\q\"\n  |           ^",
+		},
+		{
+			"", "\tx = 1979-13-01", 1, 11, 10,
+			"toml: line 1, column 11: month out of range: 13",
+			"1 | \tx = 1979-13-01\n  | \t         ^",
+		},
+		{
+			"", "a = 1\n\n\n\n\n\n\n\n\nb = [1 2]", 10, 8, 21,
+			"toml: line 10, column 8: expected `,` or `]` after array element: `U+0032 '2'`",
+			"10 | b = [1 2]\n   |        ^",
+		},
+	} {
+		_, err := Parse(test.name, test.input)
+		var se *SyntaxError
+		if !errors.As(err, &se) {
+			t.Errorf("%q: got error %v, expected a *SyntaxError", test.input, err)
+			continue
+		}
+		if se.Line != test.line || se.Column != test.column || se.Offset != test.offset {
+			t.Errorf("%q: got line %d column %d offset %d, expected line %d column %d offset %d",
+				test.input, se.Line, se.Column, se.Offset, test.line, test.column, test.offset)
+		}
+		if got := se.Error(); got != test.want {
+			t.Errorf("%q: got error\n\t%s\nexpected\n\t%s", test.input, got, test.want)
+		}
+		if got := se.Snippet(); got != test.snippet {
+			t.Errorf("%q: got snippet\n%s\nexpected\n%s", test.input, got, test.snippet)
+		}
+		if got, want := fmt.Sprintf("%+v", err), test.want+"\n"+test.snippet; got != want {
+			t.Errorf("%q: got %%+v\n%s\nexpected\n%s", test.input, got, want)
+		}
+		if got := fmt.Sprintf("%v", err); got != test.want {
+			t.Errorf("%q: got %%v %s, expected %s", test.input, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// field represents a single field of a struct that is encoded or decoded as a key.
+type field struct {
+	name      string // the key
+	index     []int  // index sequence for reflect.Value.FieldByIndex
+	typ       reflect.Type
+	omitEmpty bool // omit the key when the field has an empty value
+	inline    bool // encode the field as an inline table
+}
+
+var fieldCache sync.Map // map[reflect.Type][]field
+
+// cachedTypeFields is like typeFields but uses a cache to avoid repeated work.
+func cachedTypeFields(t reflect.Type) []field {
+	if f, ok := fieldCache.Load(t); ok {
+		return f.([]field)
+	}
+	f, _ := fieldCache.LoadOrStore(t, typeFields(t))
+	return f.([]field)
+}
+
+// typeFields returns the fields that should be recognized for the given type.
+// Fields of embedded structs without a name in their tag are promoted, the
+// fields of a shallower depth hide the deeper ones with the same name.
+//
+// The field tag has the form `toml:"name,opt1,opt2"` where the name and the
+// options omitempty and inline are optional. A field with the tag `toml:"-"`
+// is ignored.
+func typeFields(t reflect.Type) []field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []field
+	seen := make(map[string]bool)
+	visited := make(map[reflect.Type]bool)
+	for current := []embedded{{typ: t}}; len(current) > 0; {
+		var next []embedded
+		var level []field
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if sf.Anonymous {
+					if !sf.IsExported() && (ft.Kind() != reflect.Struct || sf.Type.Kind() == reflect.Ptr) {
+						continue
+					}
+				} else if !sf.IsExported() {
+					continue
+				}
+				tag := sf.Tag.Get("toml")
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+				if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				level = append(level, field{
+					name:      name,
+					index:     index,
+					typ:       sf.Type,
+					omitEmpty: opts.contains("omitempty"),
+					inline:    opts.contains("inline"),
+				})
+			}
+		}
+		for _, f := range level {
+			if !seen[f.name] {
+				seen[f.name] = true
+				fields = append(fields, f)
+			}
+		}
+		current = next
+	}
+	return fields
+}
+
+// lookupField returns the field for key. A field whose name matches
+// the key exactly is preferred over a case-insensitive match.
+func lookupField(fields []field, key string) (field, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// tagOptions is the string following a comma in a struct field's "toml"
+// tag, or the empty string.
+type tagOptions string
+
+// parseTag splits a struct field's toml tag into its name and
+// comma-separated options.
+func parseTag(tag string) (string, tagOptions) {
+	if idx := strings.Index(tag, ","); idx != -1 {
+		return tag[:idx], tagOptions(tag[idx+1:])
+	}
+	return tag, tagOptions("")
+}
+
+// contains reports whether a comma-separated list of options
+// contains a particular name.
+func (o tagOptions) contains(name string) bool {
+	for s := string(o); s != ""; {
+		var next string
+		if i := strings.Index(s, ","); i >= 0 {
+			s, next = s[:i], s[i+1:]
+		}
+		if s == name {
+			return true
+		}
+		s = next
+	}
+	return false
+}
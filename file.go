@@ -0,0 +1,628 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A File is a TOML document that can be edited without losing its
+// formatting. Comments, blank lines, the order of keys and the way values
+// are written are kept, and String reproduces every region of the document
+// that was not edited byte for byte.
+//
+// Keys are addressed by dotted key paths such as "package.version", which
+// may name keys of [table] headers, dotted keys and inline tables. Keys of
+// [[array of tables]] elements can not be addressed.
+type File struct {
+	name   string
+	tokens []fileToken
+}
+
+// A fileToken is a token of a File with its text as written in the document.
+type fileToken struct {
+	typ   itemType
+	val   string
+	raw   string
+	style valueStyle
+}
+
+// isTrivia reports whether t is whitespace, a comment or a newline.
+func (t fileToken) isTrivia() bool {
+	return t.typ == itemWhitespace || t.typ == itemComment || t.typ == itemNewline
+}
+
+// ParseFile parses src as a TOML document that can be edited. The name is
+// used only for error reports.
+func ParseFile(name, src string) (*File, error) {
+	if _, err := Parse(name, src); err != nil {
+		return nil, err
+	}
+	return &File{name: name, tokens: tokenize(name, src)}, nil
+}
+
+// String returns the TOML document.
+func (f *File) String() string {
+	var b strings.Builder
+	for _, t := range f.tokens {
+		b.WriteString(t.raw)
+	}
+	return b.String()
+}
+
+// tokenize splits the valid TOML document src into tokens, including
+// its trivia.
+func tokenize(name, src string) []fileToken {
+	var tokens []fileToken
+	l := lex(name, src)
+	l.options.emitTrivia = true
+	for {
+		it := l.nextItem()
+		if it.typ == itemEOF || it.typ == itemError {
+			return tokens
+		}
+		tokens = append(tokens, fileToken{typ: it.typ, val: it.val, raw: src[it.pos:it.end], style: it.style})
+	}
+}
+
+// fileEntry is a key/value pair of a File.
+type fileEntry struct {
+	path    []string // the full key path
+	key     int      // index of the first key token
+	value   int      // index of the first value token
+	end     int      // index after the last value token
+	depth   int      // length of the key path of the table holding the pair
+	inline  bool     // whether the pair is in an inline table
+	inArray bool     // whether the pair is in an [[array of tables]] element
+}
+
+// fileTable is a table of a File that key/value pairs can be inserted in:
+// the root table, a [table] header or an inline table.
+type fileTable struct {
+	path   []string
+	start  int  // index of the header or of '{'; -1 for the root table
+	end    int  // index after the header or after the last key/value pair
+	inline bool // whether the table is an inline table
+	array  bool // whether the table is an [[array of tables]] element
+	empty  bool // whether the table has no key/value pairs
+}
+
+// fileIndex locates the key/value pairs and tables of a File.
+type fileIndex struct {
+	tokens  []fileToken
+	entries []fileEntry
+	tables  []fileTable
+}
+
+func (f *File) index() *fileIndex {
+	x := &fileIndex{tokens: f.tokens}
+	x.tables = append(x.tables, fileTable{start: -1, empty: true})
+	cur := 0
+	for i := x.next(0); i < len(x.tokens); {
+		switch x.tokens[i].typ {
+		case itemLeftBracket, itemDoubleLeftBracket:
+			parts, j := x.keyPath(x.next(i + 1))
+			x.tables = append(x.tables, fileTable{
+				path:  parts,
+				start: i,
+				end:   j + 1,
+				array: x.tokens[i].typ == itemDoubleLeftBracket,
+				empty: true,
+			})
+			cur = len(x.tables) - 1
+			i = x.next(j + 1)
+		default:
+			t := x.tables[cur]
+			var end int
+			end, i = x.keyValue(t.path, i, false, t.array)
+			x.tables[cur].end, x.tables[cur].empty = end, false
+		}
+	}
+	return x
+}
+
+// next returns the index of the first token at or after i that is not trivia.
+func (x *fileIndex) next(i int) int {
+	for i < len(x.tokens) && x.tokens[i].isTrivia() {
+		i++
+	}
+	return i
+}
+
+// prev returns the index of the last token before i that is not trivia, or -1.
+func (x *fileIndex) prev(i int) int {
+	for i--; i >= 0 && x.tokens[i].isTrivia(); i-- {
+	}
+	return i
+}
+
+// keyPath returns the parts of the dotted key starting at token i and the
+// index of the token after it.
+func (x *fileIndex) keyPath(i int) ([]string, int) {
+	var parts []string
+	for {
+		parts = append(parts, x.tokens[i].val)
+		j := x.next(i + 1)
+		if j == len(x.tokens) || x.tokens[j].typ != itemDot {
+			return parts, j
+		}
+		i = x.next(j + 1)
+	}
+}
+
+// keyValue indexes the key/value pair starting at token i in the table at
+// the key path prefix. It returns the index after the value and the index
+// of the next token that is not trivia.
+func (x *fileIndex) keyValue(prefix []string, i int, inline, inArray bool) (int, int) {
+	parts, j := x.keyPath(i)
+	path := append(prefix[:len(prefix):len(prefix)], parts...)
+	v := x.next(j + 1)
+	end := x.valueEnd(v)
+	x.entries = append(x.entries, fileEntry{
+		path:    path,
+		key:     i,
+		value:   v,
+		end:     end,
+		depth:   len(prefix),
+		inline:  inline,
+		inArray: inArray,
+	})
+	if x.tokens[v].typ == itemInlineTableStart {
+		t := fileTable{path: path, start: v, end: v + 1, inline: true, array: inArray, empty: true}
+		for k := x.next(v + 1); x.tokens[k].typ != itemInlineTableEnd; {
+			if x.tokens[k].typ == itemComma {
+				k = x.next(k + 1)
+				continue
+			}
+			t.end, k = x.keyValue(path, k, true, inArray)
+			t.empty = false
+		}
+		x.tables = append(x.tables, t)
+	}
+	return end, x.next(end)
+}
+
+// valueEnd returns the index after the value starting at token i.
+func (x *fileIndex) valueEnd(i int) int {
+	switch x.tokens[i].typ {
+	case itemArrayStart, itemInlineTableStart:
+	default:
+		return i + 1
+	}
+	depth := 0
+	for ; i < len(x.tokens); i++ {
+		switch x.tokens[i].typ {
+		case itemArrayStart, itemInlineTableStart:
+			depth++
+		case itemArrayEnd, itemInlineTableEnd:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// entry returns the key/value pair at the key path, or nil.
+func (x *fileIndex) entry(path []string) *fileEntry {
+	for i := range x.entries {
+		if e := &x.entries[i]; !e.inArray && equalPath(e.path, path) {
+			return e
+		}
+	}
+	return nil
+}
+
+// table returns the table at the key path, or nil.
+func (x *fileIndex) table(path []string) *fileTable {
+	for i := range x.tables {
+		if t := &x.tables[i]; !t.array && equalPath(t.path, path) {
+			return t
+		}
+	}
+	return nil
+}
+
+// arrayTable returns the length of the key path of the [[array of tables]]
+// that path is or is in, or 0 if there is none.
+func (x *fileIndex) arrayTable(path []string) int {
+	for _, t := range x.tables {
+		if t.array && !t.inline && len(t.path) <= len(path) && equalPath(t.path, path[:len(t.path)]) {
+			return len(t.path)
+		}
+	}
+	return 0
+}
+
+// commentsAbove returns the index of the first token of the block of
+// comment lines directly above the line starting at token i, or i if there
+// is none.
+func (x *fileIndex) commentsAbove(i int) int {
+	for i > 0 {
+		j := x.lineStart(i - 1)
+		k := j
+		if x.tokens[k].typ == itemWhitespace {
+			k++
+		}
+		if x.tokens[k].typ != itemComment {
+			break
+		}
+		i = j
+	}
+	return i
+}
+
+// lineStart returns the index of the first token on the line of token i.
+func (x *fileIndex) lineStart(i int) int {
+	for i > 0 && x.tokens[i-1].typ != itemNewline {
+		i--
+	}
+	return i
+}
+
+// lineEnd returns the index after the newline ending the line of the
+// token before i, or the number of tokens if the line is the last one.
+func (x *fileIndex) lineEnd(i int) int {
+	if i > 0 && x.tokens[i-1].typ == itemNewline {
+		return i
+	}
+	for ; i < len(x.tokens); i++ {
+		if x.tokens[i].typ == itemNewline {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// newline returns the newline ending the line of token i, or the newline
+// of the nearest line before it, so that added lines match their
+// neighbours. It is LF if the document has no newline.
+func (x *fileIndex) newline(i int) string {
+	for j := i; j < len(x.tokens); j++ {
+		if x.tokens[j].typ == itemNewline {
+			return x.tokens[j].raw
+		}
+	}
+	for j := i - 1; j >= 0; j-- {
+		if x.tokens[j].typ == itemNewline {
+			return x.tokens[j].raw
+		}
+	}
+	return "\n"
+}
+
+func equalPath(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Set sets the value of the key at the dotted key path. The value of an
+// existing key is replaced in place, keeping the comments and formatting
+// around it. A key that does not exist yet is inserted as by Insert.
+//
+// The value is encoded like the values of Marshal, tables as inline tables.
+// A string replacing a string keeps its quoting style, and an integer
+// replacing an integer keeps its base and the size of its digit groups,
+// if the new value can be written that way.
+func (f *File) Set(path string, value interface{}) error {
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return err
+	}
+	x := f.index()
+	e := x.entry(parts)
+	if e == nil {
+		if x.table(parts) != nil {
+			return fmt.Errorf("toml: %s is a table", formatKey(parts))
+		}
+		return f.insert(x, parts, value)
+	}
+	raw, err := encodeFileValue(parts, value)
+	if err != nil {
+		return err
+	}
+	if e.end == e.value+1 {
+		raw = restyle(f.tokens[e.value], value, raw)
+	}
+	return f.splice(e.value, e.end, raw)
+}
+
+// Insert adds the key at the dotted key path with the given value. It
+// reports an error if the key already exists.
+//
+// The key is added after the last key/value pair of its table, or of its
+// inline table. If the table does not exist, a [table] header holding the
+// key is added at the end of the document.
+func (f *File) Insert(path string, value interface{}) error {
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return err
+	}
+	x := f.index()
+	if x.entry(parts) != nil || x.table(parts) != nil {
+		return fmt.Errorf("toml: key %s is already defined", formatKey(parts))
+	}
+	return f.insert(x, parts, value)
+}
+
+func (f *File) insert(x *fileIndex, parts []string, value interface{}) error {
+	if n := x.arrayTable(parts); n == len(parts) {
+		return fmt.Errorf("toml: %s is an array of tables and can not be addressed", formatKey(parts))
+	} else if n > 0 {
+		return fmt.Errorf("toml: %s is in an array of tables and can not be addressed", formatKey(parts))
+	}
+	raw, err := encodeFileValue(parts, value)
+	if err != nil {
+		return err
+	}
+	parent, key := parts[:len(parts)-1], parts[len(parts)-1:]
+	t := x.table(parent)
+	if t == nil {
+		// The table may be defined by dotted keys, add a dotted key after
+		// the last of them.
+		for i := len(x.entries) - 1; i >= 0; i-- {
+			e := &x.entries[i]
+			if !e.inArray && e.depth <= len(parent) && len(e.path) > len(parent) && equalPath(e.path[:len(parent)], parent) {
+				if e.inline {
+					return f.splice(e.end, e.end, ", "+formatKey(parts[e.depth:])+" = "+raw)
+				}
+				return f.insertLine(x, e.end, formatKey(parts[e.depth:])+" = "+raw)
+			}
+		}
+		// Otherwise add a dotted key to the innermost inline table holding
+		// the key, or a new [table] at the end of the document.
+		for n := len(parent) - 1; n > 0; n-- {
+			if p := x.table(parent[:n]); p != nil && p.inline {
+				t, key = p, parts[n:]
+				break
+			}
+		}
+	}
+	kv := formatKey(key) + " = " + raw
+	switch {
+	case t == nil:
+		lines := []string{"[" + formatKey(parent) + "]", kv}
+		if len(x.tokens) > 0 {
+			// Separate the new table by a blank line.
+			lines = append([]string{""}, lines...)
+		}
+		return f.appendLines(x, lines...)
+	case t.inline && t.empty:
+		return f.splice(t.start+1, x.next(t.start+1), " "+kv+" ")
+	case t.inline:
+		return f.splice(t.end, t.end, ", "+kv)
+	case t.empty && t.start < 0:
+		// Add the key before the first [table] header and the comment
+		// lines directly above it, but below the comments at the top of
+		// the document.
+		if len(x.tables) == 1 {
+			return f.appendLines(x, kv)
+		}
+		i := x.lineStart(x.tables[1].start)
+		if j := x.commentsAbove(i); j > 0 {
+			i = j
+		}
+		return f.splice(i, i, kv+x.newline(i))
+	}
+	return f.insertLine(x, t.end, kv)
+}
+
+// appendLines adds the lines to the end of the document, separated by the
+// newline the document uses.
+func (f *File) appendLines(x *fileIndex, lines ...string) error {
+	nl := x.newline(len(x.tokens))
+	s := f.String()
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += nl
+	}
+	return f.reload(s + strings.Join(lines, nl) + nl)
+}
+
+// insertLine inserts the line s after the line of the token before i,
+// indented like that line.
+func (f *File) insertLine(x *fileIndex, i int, s string) error {
+	if first := x.lineStart(i - 1); x.tokens[first].typ == itemWhitespace {
+		s = x.tokens[first].raw + s
+	}
+	nl := x.newline(i - 1)
+	j := x.lineEnd(i)
+	if j == len(x.tokens) && x.tokens[j-1].typ != itemNewline {
+		s = nl + s
+	}
+	return f.splice(j, j, s+nl)
+}
+
+// Delete removes the key at the dotted key path, or the [table] at the
+// path with all of its key/value pairs. A key is removed with the rest of
+// its line, a key of an inline table with its separating comma.
+func (f *File) Delete(path string) error {
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return err
+	}
+	x := f.index()
+	if e := x.entry(parts); e != nil {
+		if !e.inline {
+			return f.splice(x.lineStart(e.key), x.lineEnd(e.end), "")
+		}
+		if next := x.next(e.end); x.tokens[next].typ == itemComma {
+			return f.splice(e.key, x.next(next+1), "")
+		}
+		if prev := x.prev(e.key); x.tokens[prev].typ == itemComma {
+			return f.splice(prev, e.end, "")
+		}
+		// The only key/value pair of the inline table.
+		return f.splice(x.prev(e.key)+1, x.next(e.end), "")
+	}
+	if t := x.table(parts); t != nil && !t.inline && t.start >= 0 {
+		return f.splice(x.lineStart(t.start), x.lineEnd(t.end), "")
+	}
+	return fmt.Errorf("toml: key %s is not defined", formatKey(parts))
+}
+
+// splice replaces the tokens from i to j with the text s. The document must
+// remain valid, otherwise the File is left unchanged.
+func (f *File) splice(i, j int, s string) error {
+	var b strings.Builder
+	for _, t := range f.tokens[:i] {
+		b.WriteString(t.raw)
+	}
+	b.WriteString(s)
+	for _, t := range f.tokens[j:] {
+		b.WriteString(t.raw)
+	}
+	return f.reload(b.String())
+}
+
+// reload replaces the document of f with src if it is valid.
+func (f *File) reload(src string) error {
+	g, err := ParseFile(f.name, src)
+	if err != nil {
+		return err
+	}
+	f.tokens = g.tokens
+	return nil
+}
+
+// encodeFileValue returns the TOML encoding of the value of the key path.
+func encodeFileValue(path []string, value interface{}) (string, error) {
+	e := &encodeState{}
+	if err := e.value(path, reflect.ValueOf(value)); err != nil {
+		return "", err
+	}
+	return e.String(), nil
+}
+
+// restyle returns the encoding raw of value written in the style of the
+// value old it replaces, if possible.
+func restyle(old fileToken, value interface{}, raw string) string {
+	v := indirectValue(reflect.ValueOf(value))
+	switch {
+	case old.typ == itemStringValue && v.Kind() == reflect.String:
+		if s, ok := quoteStringStyle(v.String(), old.style); ok {
+			return s
+		}
+	case old.typ == itemIntegerValue:
+		if s, ok := formatInteger(v, old.style, old.raw); ok {
+			return s
+		}
+	}
+	return raw
+}
+
+// quoteStringStyle returns s as a string of the given style. It reports
+// false if s can not be written in that style.
+func quoteStringStyle(s string, style valueStyle) (string, bool) {
+	// A newline right after the opening delimiter of a multi-line
+	// string is trimmed.
+	lead := ""
+	if strings.HasPrefix(s, "\n") {
+		lead = "\n"
+	}
+	switch style {
+	case styleLiteral:
+		if strings.ContainsRune(s, '\'') || strings.IndexFunc(s, isControl) >= 0 {
+			return "", false
+		}
+		return "'" + s + "'", true
+	case styleMultiLineLiteral:
+		isInvalid := func(r rune) bool { return r != '\n' && isControl(r) }
+		if strings.Contains(s, "'''") || strings.IndexFunc(s, isInvalid) >= 0 {
+			return "", false
+		}
+		return "'''" + lead + s + "'''", true
+	case styleMultiLineBasic:
+		return `"""` + lead + escapeString(s, true) + `"""`, true
+	}
+	return "", false
+}
+
+// formatInteger returns the integer v in the base of the given style, with
+// its digits grouped like the last digit group of the integer raw. It
+// reports false if v is not an integer or can not be written in that base.
+func formatInteger(v reflect.Value, style valueStyle, raw string) (string, bool) {
+	var (
+		u    uint64
+		sign string
+	)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := v.Int()
+		u = uint64(n)
+		if n < 0 {
+			sign, u = "-", -u
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u = v.Uint()
+	default:
+		return "", false
+	}
+	base, prefix := integerBase(style), ""
+	switch base {
+	case 16:
+		prefix = "0x"
+	case 8:
+		prefix = "0o"
+	case 2:
+		prefix = "0b"
+	}
+	// Hexadecimal, octal and binary integers may not have a sign.
+	if sign != "" && prefix != "" {
+		return "", false
+	}
+	digits := strconv.FormatUint(u, base)
+	if base == 16 && strings.ContainsAny(raw, "ABCDEF") {
+		digits = strings.ToUpper(digits)
+	}
+	if groups := digitGroups(raw); len(groups) > 1 {
+		digits = groupDigits(digits, groups[len(groups)-1])
+	}
+	return sign + prefix + digits, true
+}
+
+// groupDigits separates the digits into groups of n from the right with
+// underscores.
+func groupDigits(digits string, n int) string {
+	if n <= 0 {
+		return digits
+	}
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%n == 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// splitKeyPath splits the dotted key path into its parts. The parts may be
+// bare or quoted keys.
+func splitKeyPath(path string) ([]string, error) {
+	l := lex("", path+"=0")
+	var parts []string
+	for {
+		it := l.nextItem()
+		switch it.typ {
+		case itemKey:
+			parts = append(parts, it.val)
+			continue
+		case itemDot:
+			continue
+		case itemEqual:
+			if len(parts) > 0 && int(it.pos) == len(path) {
+				return parts, nil
+			}
+		}
+		return nil, fmt.Errorf("toml: invalid key path %q", path)
+	}
+}
@@ -0,0 +1,323 @@
+package toml
+
+import (
+	"strings"
+	"testing"
+)
+
+const cargoManifest = `# The manifest of the example crate.
+[package]
+name = 'example'   # single quotes are kept
+version = "0.1.0"
+authors = [
+  "Jane Doe <jane@example.com>", # the maintainer
+]
+
+[dependencies]
+log = "0.4"
+serde = { version = "1.0", features = ["derive"] }
+
+[[bin]]
+name = "example"
+`
+
+func TestFileRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		cargoManifest,
+		"",
+		"# only a comment",
+		"a = 1",
+		"a = 1\r\nb = 2\r\n",
+		"\n\n  key   =   \"value\"  # comment\n\n",
+		"\"quoted key\" = 'literal'\n'literal key' = \"\"\"\nmulti\"\"line\"\"\"\"\"\n",
+		"s = '''\nit's\n'''''\nt = \"esc \\\" \\\\\"\n",
+		"a.b . c = { d = [1, 2, [3]], e = {} }\n[ x . y ]\n[[z]]\n",
+		"t = 1979-05-27T07:32:00Z\nf = -1e10\nb = true\n",
+	} {
+		f, err := ParseFile("", input)
+		if err != nil {
+			t.Errorf("%q: %v", input, err)
+			continue
+		}
+		if got := f.String(); got != input {
+			t.Errorf("got\n%s\nexpected\n%s", got, input)
+		}
+	}
+}
+
+func TestFileParseError(t *testing.T) {
+	_, err := ParseFile("Cargo.toml", "a = 1\na = 2\n")
+	want := "toml: Cargo.toml:2:1: key a is already defined as a value at line 1, column 1"
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+}
+
+func TestFileEdit(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		input string
+		edit  func(f *File) error
+		want  string
+	}{
+		{
+			"set version",
+			cargoManifest,
+			func(f *File) error { return f.Set("package.version", "0.2.0") },
+			strings.Replace(cargoManifest, `"0.1.0"`, `"0.2.0"`, 1),
+		},
+		{
+			"set in inline table",
+			cargoManifest,
+			func(f *File) error { return f.Set("dependencies.serde.version", "1.1") },
+			strings.Replace(cargoManifest, `"1.0"`, `"1.1"`, 1),
+		},
+		{
+			"set array",
+			cargoManifest,
+			func(f *File) error { return f.Set("dependencies.serde.features", []string{"derive", "std"}) },
+			strings.Replace(cargoManifest, `["derive"]`, `["derive", "std"]`, 1),
+		},
+		{
+			"set keeps comment",
+			"a = 1 # one\n",
+			func(f *File) error { return f.Set("a", 2) },
+			"a = 2 # one\n",
+		},
+		{
+			"set dotted key",
+			"a.b = 1\n",
+			func(f *File) error { return f.Set(`a."b"`, 2.5) },
+			"a.b = 2.5\n",
+		},
+		{
+			"set keeps literal string",
+			cargoManifest,
+			func(f *File) error { return f.Set("package.name", "renamed") },
+			strings.Replace(cargoManifest, "'example'", "'renamed'", 1),
+		},
+		{
+			"set string not literal",
+			"a = 'x'\n",
+			func(f *File) error { return f.Set("a", "it's") },
+			"a = \"it's\"\n",
+		},
+		{
+			"set multi-line strings",
+			"a = \"\"\"x\"\"\"\nb = '''\ny'''\n",
+			func(f *File) error {
+				if err := f.Set("a", "\"1\"\n2"); err != nil {
+					return err
+				}
+				return f.Set("b", "\n3")
+			},
+			"a = \"\"\"\\\"1\\\"\n2\"\"\"\nb = '''\n\n3'''\n",
+		},
+		{
+			"set keeps integer style",
+			"a = 0xDEAD_BEEF\nb = 1_000\nc = 0b1\nd = 0o7\n",
+			func(f *File) error {
+				for k, v := range map[string]interface{}{"a": 0xcafebabe, "b": -1234567, "c": uint8(5), "d": 64} {
+					if err := f.Set(k, v); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			"a = 0xCAFE_BABE\nb = -1_234_567\nc = 0b101\nd = 0o100\n",
+		},
+		{
+			"set negative hex",
+			"a = 0xff\n",
+			func(f *File) error { return f.Set("a", -1) },
+			"a = -1\n",
+		},
+		{
+			"set inserts",
+			"a = 1\n",
+			func(f *File) error { return f.Set("b", true) },
+			"a = 1\nb = true\n",
+		},
+		{
+			"insert after last key",
+			cargoManifest,
+			func(f *File) error { return f.Insert("package.edition", "2021") },
+			strings.Replace(cargoManifest, "]\n\n[dependencies]", "]\nedition = \"2021\"\n\n[dependencies]", 1),
+		},
+		{
+			"insert indented",
+			"[a]\n  x = 1 # x\n\n# end\n",
+			func(f *File) error { return f.Insert("a.y", 2) },
+			"[a]\n  x = 1 # x\n  y = 2\n\n# end\n",
+		},
+		{
+			"insert into empty table",
+			"[a]\n[b]\n",
+			func(f *File) error { return f.Insert("a.x", 1) },
+			"[a]\nx = 1\n[b]\n",
+		},
+		{
+			"insert into root",
+			"# comment\n[a]\n",
+			func(f *File) error { return f.Insert("x", 1) },
+			"# comment\nx = 1\n[a]\n",
+		},
+		{
+			"insert into root above a commented table",
+			"# top\n\n# about a\n  # more\n[a]\n",
+			func(f *File) error { return f.Insert("x", 1) },
+			"# top\n\nx = 1\n# about a\n  # more\n[a]\n",
+		},
+		{
+			"insert into root without tables",
+			"# comment\n",
+			func(f *File) error { return f.Insert("x", 1) },
+			"# comment\nx = 1\n",
+		},
+		{
+			"insert into empty document",
+			"",
+			func(f *File) error { return f.Insert("x", 1) },
+			"x = 1\n",
+		},
+		{
+			"insert with CRLF",
+			"a = 1\r\nb = 2\r\n",
+			func(f *File) error { return f.Insert("c", 3) },
+			"a = 1\r\nb = 2\r\nc = 3\r\n",
+		},
+		{
+			"insert into root with CRLF",
+			"# top\r\n[package]\r\nname = 'x'\r\n",
+			func(f *File) error { return f.Insert("root", 1) },
+			"# top\r\nroot = 1\r\n[package]\r\nname = 'x'\r\n",
+		},
+		{
+			"insert new table with CRLF",
+			"a = 1\r\n",
+			func(f *File) error { return f.Insert("b.c", 2) },
+			"a = 1\r\n\r\n[b]\r\nc = 2\r\n",
+		},
+		{
+			"insert without newline at end",
+			"a = 1",
+			func(f *File) error { return f.Insert("b", 2) },
+			"a = 1\nb = 2\n",
+		},
+		{
+			"insert into inline table",
+			cargoManifest,
+			func(f *File) error { return f.Insert("dependencies.serde.optional", true) },
+			strings.Replace(cargoManifest, `["derive"] }`, `["derive"], optional = true }`, 1),
+		},
+		{
+			"insert into empty inline table",
+			"a = {}\n",
+			func(f *File) error { return f.Insert("a.b", 1) },
+			"a = { b = 1 }\n",
+		},
+		{
+			"insert dotted key into inline table",
+			"a = { b = 1 }\n",
+			func(f *File) error { return f.Insert("a.c.d", 2) },
+			"a = { b = 1, c.d = 2 }\n",
+		},
+		{
+			"insert next to dotted keys",
+			"a.b = 1\nc = 2\n",
+			func(f *File) error { return f.Insert("a.x", 3) },
+			"a.b = 1\na.x = 3\nc = 2\n",
+		},
+		{
+			"insert new table",
+			"a = 1",
+			func(f *File) error { return f.Insert(`b."c d".e`, "f") },
+			"a = 1\n\n[b.\"c d\"]\ne = \"f\"\n",
+		},
+		{
+			"delete key",
+			cargoManifest,
+			func(f *File) error { return f.Delete("dependencies.log") },
+			strings.Replace(cargoManifest, "log = \"0.4\"\n", "", 1),
+		},
+		{
+			"delete multi-line value",
+			cargoManifest,
+			func(f *File) error { return f.Delete("package.authors") },
+			strings.Replace(cargoManifest, "authors = [\n  \"Jane Doe <jane@example.com>\", # the maintainer\n]\n", "", 1),
+		},
+		{
+			"delete first in inline table",
+			"a = { b = 1, c = 2 }\n",
+			func(f *File) error { return f.Delete("a.b") },
+			"a = { c = 2 }\n",
+		},
+		{
+			"delete last in inline table",
+			"a = { b = 1, c = 2 }\n",
+			func(f *File) error { return f.Delete("a.c") },
+			"a = { b = 1 }\n",
+		},
+		{
+			"delete only in inline table",
+			"a = { b = 1 }\n",
+			func(f *File) error { return f.Delete("a.b") },
+			"a = {}\n",
+		},
+		{
+			"delete table",
+			"[a]\nx = 1\n\n# b\n[b]\ny = 2\n",
+			func(f *File) error { return f.Delete("a") },
+			"\n# b\n[b]\ny = 2\n",
+		},
+	} {
+		f, err := ParseFile("", test.input)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if err := test.edit(f); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got := f.String(); got != test.want {
+			t.Errorf("%s: got\n%s\nexpected\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestFileEditError(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		edit func(f *File) error
+		want string
+	}{
+		{"invalid path", func(f *File) error { return f.Set("a..b", 1) }, `toml: invalid key path "a..b"`},
+		{"empty path", func(f *File) error { return f.Delete("") }, `toml: invalid key path ""`},
+		{"set table", func(f *File) error { return f.Set("package", 1) }, "toml: package is a table"},
+		{"set nil", func(f *File) error { return f.Set("package.name", nil) }, "toml: package.name: cannot encode nil"},
+		{"insert existing", func(f *File) error { return f.Insert("package.name", "x") }, "toml: key package.name is already defined"},
+		{"delete missing", func(f *File) error { return f.Delete("package.license") }, "toml: key package.license is not defined"},
+		{"array of tables", func(f *File) error { return f.Delete("bin.name") }, "toml: key bin.name is not defined"},
+		{"set in array of tables", func(f *File) error { return f.Set("bin.name", "x") }, "toml: bin.name is in an array of tables and can not be addressed"},
+		{"insert in array of tables", func(f *File) error { return f.Insert("bin.x.y", 1) }, "toml: bin.x.y is in an array of tables and can not be addressed"},
+		{"set array of tables", func(f *File) error { return f.Set("bin", 1) }, "toml: bin is an array of tables and can not be addressed"},
+		{
+			"invalid result",
+			func(f *File) error { return f.Insert("package.name.first", "x") },
+			"toml: line 16, column 1: key package.name is already defined as a value at line 3, column 1",
+		},
+	} {
+		f, err := ParseFile("", cargoManifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = test.edit(f)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, expected %s", test.name, err, test.want)
+		}
+		if got := f.String(); got != cargoManifest {
+			t.Errorf("%s: document changed to\n%s", test.name, got)
+		}
+	}
+}
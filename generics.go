@@ -0,0 +1,57 @@
+//go:build go1.18
+
+package toml
+
+import "reflect"
+
+// Get returns the value of the key path in doc decoded into a T, by the
+// same rules as Unmarshal. The path is a dotted key, such as
+// "server.tls.cert", and may name a table, an array of tables or a value.
+func Get[T any](doc *Document, path string) (T, error) {
+	var v T
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return v, err
+	}
+	root, err := buildTable(doc)
+	if err != nil {
+		return v, err
+	}
+	val, err := lookupTable(root, parts)
+	if err != nil {
+		return v, err
+	}
+	err = decodeValue(val, reflect.ValueOf(&v).Elem(), parts)
+	return v, err
+}
+
+// DecodeAs parses the TOML document data and returns it decoded into a T,
+// as Unmarshal does.
+func DecodeAs[T any](data []byte) (T, error) {
+	var v T
+	err := Unmarshal(data, &v)
+	return v, err
+}
+
+// lookupTable returns the value of the key parts in the table t, which is a
+// Node, *table or *tableArray.
+func lookupTable(t *table, parts []string) (interface{}, error) {
+	for i, part := range parts {
+		val, ok := t.values[part]
+		if !ok {
+			return nil, decodeError(nil, nil, "key %s is not defined", formatKey(parts))
+		}
+		if i == len(parts)-1 {
+			return val, nil
+		}
+		switch v := val.(type) {
+		case *table:
+			t = v
+		case *tableArray:
+			return nil, decodeError(v.node, parts[:i+1], "array of tables is not a table")
+		case Node:
+			return nil, decodeError(v, parts[:i+1], "%s is not a table", describeNode(v))
+		}
+	}
+	panic("toml: empty key path")
+}
@@ -0,0 +1,112 @@
+//go:build go1.18
+
+package toml
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	doc, err := Parse("", decodeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := Get[uint16](doc, "server.PORT")
+	if err != nil || port != 8080 {
+		t.Errorf("server.PORT: got %d, %v, expected 8080", port, err)
+	}
+	timeout, err := Get[float32](doc, "server.timeout")
+	if err != nil || timeout != 5 {
+		t.Errorf("server.timeout: got %v, %v, expected 5", timeout, err)
+	}
+	released, err := Get[time.Time](doc, "released")
+	if want := time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC); err != nil || !released.Equal(want) {
+		t.Errorf("released: got %v, %v, expected %v", released, err, want)
+	}
+	server, err := Get[decodeServer](doc, "server")
+	if want := (decodeServer{Host: "localhost", Port: 8080, Enabled: true, Timeout: 5, Tags: []string{"a", "b"}}); err != nil || !reflect.DeepEqual(server, want) {
+		t.Errorf("server: got %+v, %v, expected %+v", server, err, want)
+	}
+	products, err := Get[[]decodeProduct](doc, "products")
+	if err != nil || len(products) != 3 || products[0].Name != "Hammer" {
+		t.Errorf("products: got %+v, %v", products, err)
+	}
+	v, err := Get[interface{}](doc, "matrix")
+	if want := []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3)}}; err != nil || !reflect.DeepEqual(v, want) {
+		t.Errorf("matrix: got %#v, %v, expected %#v", v, err, want)
+	}
+	n, err := Get[*big.Int](doc, "version")
+	if err != nil || n.Int64() != 2 {
+		t.Errorf("version: got %v, %v, expected 2", n, err)
+	}
+
+	doc, err = Parse("", "a = { b = { c = 1979-05-27 } }\nlevel = 'info'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Get[LocalDate](doc, "a.b.c")
+	if want := (LocalDate{1979, time.May, 27}); err != nil || d != want {
+		t.Errorf("a.b.c: got %v, %v, expected %v", d, err, want)
+	}
+	level, err := Get[decodeLevel](doc, "level")
+	if err != nil || level != 1 {
+		t.Errorf("level: got %v, %v, expected 1", level, err)
+	}
+	// Get reads the nodes of doc as they are now.
+	doc.Nodes = doc.Nodes[1:]
+	if _, err := Get[LocalDate](doc, "a.b.c"); err == nil {
+		t.Errorf("a.b.c: got no error after removing a")
+	}
+}
+
+func TestGetError(t *testing.T) {
+	doc, err := Parse("", decodeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		get  func() error
+		want string
+	}{
+		{"type", func() error { _, err := Get[string](doc, "server.PORT"); return err }, "toml: line 10: server.PORT: cannot decode integer 8080 into Go value of type string"},
+		{"overflow", func() error { _, err := Get[int8](doc, "server.PORT"); return err }, "toml: line 10: server.PORT: integer 8080 overflows Go value of type int8"},
+		{"missing", func() error { _, err := Get[int](doc, "server.user"); return err }, "toml: key server.user is not defined"},
+		{"not a table", func() error { _, err := Get[int](doc, "title.x"); return err }, "toml: line 2: title: string is not a table"},
+		{"array of tables", func() error { _, err := Get[int](doc, "products.sku"); return err }, "toml: line 20: products: array of tables is not a table"},
+		{"invalid path", func() error { _, err := Get[int](doc, "a..b"); return err }, `toml: invalid key path "a..b"`},
+	} {
+		if err := test.get(); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, expected %s", test.name, err, test.want)
+		}
+	}
+}
+
+func TestDecodeAs(t *testing.T) {
+	c, err := DecodeAs[decodeConfig]([]byte(decodeInput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Title != "TOML Example" || c.Server.Port != 8080 || c.Version != 2 {
+		t.Errorf("got %+v", c)
+	}
+	p, err := DecodeAs[*decodeServer]([]byte("host = 'example.com'"))
+	if err != nil || p == nil || p.Host != "example.com" {
+		t.Errorf("got %+v, %v, expected host example.com", p, err)
+	}
+	m, err := DecodeAs[map[string]int]([]byte("a = 1\nb = 2"))
+	if want := map[string]int{"a": 1, "b": 2}; err != nil || !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, %v, expected %v", m, err, want)
+	}
+	_, err = DecodeAs[map[string]string]([]byte("a = 1"))
+	if want := "toml: line 1: a: cannot decode integer 1 into Go value of type string"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+	_, err = DecodeAs[int]([]byte("a = 1"))
+	if want := "toml: cannot decode table into Go value of type int"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+}
@@ -0,0 +1,163 @@
+// Package tag converts between the Go values of decoded TOML documents and
+// the tagged JSON format of toml-test.
+//
+// In tagged JSON a table is a JSON object and an array is a JSON array,
+// every other value is an object holding its TOML type and its value as a
+// string:
+//
+//	{"type": "integer", "value": "42"}
+//
+// The types are string, integer, float, bool, datetime, datetime-local,
+// date-local and time-local.
+//
+// See https://github.com/toml-lang/toml-test for the details.
+package tag
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	toml "github.com/Code-Hex/go-toml"
+)
+
+const layoutDateTime = "2006-01-02T15:04:05.999999999Z07:00"
+
+// Add returns the tagged JSON value of v, a value decoded by Unmarshal
+// into an empty interface.
+func Add(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			var err error
+			if m[k], err = Add(e); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []map[string]interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if a[i], err = Add(e); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if a[i], err = Add(e); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case string:
+		return tag("string", v), nil
+	case int64:
+		return tag("integer", strconv.FormatInt(v, 10)), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return tag("float", "nan"), nil
+		case math.IsInf(v, 1):
+			return tag("float", "inf"), nil
+		case math.IsInf(v, -1):
+			return tag("float", "-inf"), nil
+		}
+		return tag("float", strconv.FormatFloat(v, 'g', -1, 64)), nil
+	case bool:
+		return tag("bool", strconv.FormatBool(v)), nil
+	case time.Time:
+		return tag("datetime", v.Format(layoutDateTime)), nil
+	case toml.LocalDateTime:
+		return tag("datetime-local", v.String()), nil
+	case toml.LocalDate:
+		return tag("date-local", v.String()), nil
+	case toml.LocalTime:
+		return tag("time-local", v.String()), nil
+	}
+	return nil, fmt.Errorf("tag: unexpected value of type %T", v)
+}
+
+func tag(typ, value string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "value": value}
+}
+
+// Remove returns the Go value of the tagged JSON value v, as decoded by
+// encoding/json into an empty interface. Tables become maps, arrays become
+// slices and the other values become string, int64, float64, bool,
+// time.Time, toml.LocalDateTime, toml.LocalDate or toml.LocalTime.
+func Remove(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if typ, ok := v["type"].(string); ok && len(v) == 2 {
+			if value, ok := v["value"].(string); ok {
+				return untag(typ, value)
+			}
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			var err error
+			if m[k], err = Remove(e); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if a[i], err = Remove(e); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	return nil, fmt.Errorf("tag: unexpected JSON value of type %T", v)
+}
+
+func untag(typ, value string) (interface{}, error) {
+	var (
+		v   interface{}
+		err error
+	)
+	switch typ {
+	case "string":
+		return value, nil
+	case "integer":
+		v, err = strconv.ParseInt(value, 10, 64)
+	case "float":
+		v, err = strconv.ParseFloat(value, 64)
+	case "bool":
+		v, err = strconv.ParseBool(value)
+	case "datetime":
+		v, err = time.Parse(layoutDateTime, value)
+	case "datetime-local":
+		v, err = unmarshalText(&toml.LocalDateTime{}, value)
+	case "date-local":
+		v, err = unmarshalText(&toml.LocalDate{}, value)
+	case "time-local":
+		v, err = unmarshalText(&toml.LocalTime{}, value)
+	default:
+		return nil, fmt.Errorf("tag: unknown type %q", typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tag: invalid %s %q: %v", typ, value, err)
+	}
+	return v, nil
+}
+
+// unmarshalText unmarshals value into the pointer p and returns the value
+// p points to.
+func unmarshalText(p encoding.TextUnmarshaler, value string) (interface{}, error) {
+	if err := p.UnmarshalText([]byte(value)); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(p).Elem().Interface(), nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // https://github.com/toml-lang/toml
@@ -14,24 +15,68 @@ const (
 	itemError itemType = iota
 	itemEOF
 	itemKey
+	itemDot // the dot separating dotted keys
 	itemEqual
+	itemLeftBracket        // '[' opening a table header
+	itemRightBracket       // ']' closing a table header
+	itemDoubleLeftBracket  // '[[' opening an array of tables header
+	itemDoubleRightBracket // ']]' closing an array of tables header
+	itemArrayStart         // '[' opening an array value
+	itemArrayEnd           // ']' closing an array value
+	itemInlineTableStart   // '{' opening an inline table value
+	itemInlineTableEnd     // '}' closing an inline table value
+	itemComma              // ',' separating array elements or inline table key/value pairs
 	itemStringValue
 	itemIntegerValue
 	itemFloatValue
 	itemBooleanValue
 	itemTimeValue
+	itemComment    // '#' and the text of a comment, without the newline
+	itemNewline    // LF or CRLF
+	itemWhitespace // run of spaces and tabs
 )
 
 const (
 	eof = -1
 )
 
+// valueStyle records how a string or an integer was written.
+type valueStyle uint8
+
+const (
+	styleNone             valueStyle = iota
+	styleBasic                       // "basic string"
+	styleLiteral                     // 'literal string'
+	styleMultiLineBasic              // """multi-line basic string"""
+	styleMultiLineLiteral            // '''multi-line literal string'''
+	styleDecimal                     // decimal integer
+	styleHex                         // hexadecimal integer with prefix 0x
+	styleOctal                       // octal integer with prefix 0o
+	styleBinary                      // binary integer with prefix 0b
+)
+
 // item represents a token or text string returned from the scanner.
 type item struct {
-	typ  itemType // The type of this item.
-	pos  Pos      // The starting position, in bytes, of this item in the input string.
-	val  string   // The value of this item.
-	line int      // The line number at the start of this item.
+	typ   itemType   // The type of this item.
+	pos   Pos        // The starting position, in bytes, of this item in the input string.
+	end   Pos        // The position after the raw text of this item in the input string.
+	val   string     // The value of this item.
+	line  int        // The line number at the start of this item.
+	style valueStyle // How a string or an integer value, or a quoted key, was written.
+}
+
+func (i item) String() string {
+	switch {
+	case i.typ == itemEOF:
+		return "EOF"
+	case i.typ == itemError:
+		return i.val
+	case i.typ == itemKey:
+		return fmt.Sprintf("key %q", i.val)
+	case len(i.val) > 10:
+		return fmt.Sprintf("%.10q...", i.val)
+	}
+	return fmt.Sprintf("%q", i.val)
 }
 
 // stateFn represents the state of the scanner as a function that returns the next state.
@@ -39,63 +84,91 @@ type stateFn func(*lexer) stateFn
 
 // lexer holds the state of the scanner.
 type lexer struct {
-	name      string    // the name of the input; used only for error reports
-	input     string    // the string being scanned
-	pos       Pos       // current position in the input
-	start     Pos       // start position of this item
-	items     chan item // channel of scanned items
-	line      int       // 1+number of newlines seen
-	startLine int       // start line of this item
+	name      string  // the name of the input; used only for error reports
+	input     string  // the string being scanned
+	pos       Pos     // current position in the input
+	start     Pos     // start position of this item
+	width     Pos     // width of last rune read from input
+	state     stateFn // the next state to run; nil once scanning is done
+	items     []item  // scanned items not yet returned by nextItem
+	head      int     // index of the next item to return in items
+	line      int     // 1+number of newlines seen
+	startLine int     // start line of this item
+	nest      []nest  // arrays and inline tables being scanned, innermost last
+	header    bool    // whether a table header is being scanned
+	options   lexOptions
 
 	buf strings.Builder
 }
 
+// lexOptions control the behavior of the lexer.
+type lexOptions struct {
+	emitTrivia bool // emit itemComment, itemNewline and itemWhitespace instead of discarding them
+}
+
+// nest holds the state of an array or inline table value being scanned.
+type nest struct {
+	typ        itemType // itemArrayStart or itemInlineTableStart
+	afterValue bool     // whether the last element was a value
+	afterComma bool     // whether the last element was a comma
+}
+
 // lex creates a new scanner for the input string.
+// Nothing is scanned until nextItem is called.
 func lex(name, input string) *lexer {
 	l := &lexer{
 		name:      name,
 		input:     input,
-		items:     make(chan item),
+		state:     lexText,
 		line:      1,
 		startLine: 1,
 	}
-	go l.run()
-	return l
-}
-
-// run runs the state machine for the lexer.
-func (l *lexer) run() {
-	for state := lexText; state != nil; {
-		state = state(l)
+	// https://github.com/toml-lang/toml#spec
+	// A TOML file must be a valid UTF-8 encoded Unicode document.
+	if !utf8.ValidString(input) {
+		l.state = lexInvalidUTF8
 	}
-	close(l.items)
+	return l
 }
 
+// next returns the next rune in the input.
 func (l *lexer) next() rune {
 	if l.isEOF() {
+		l.width = 0
 		return eof
 	}
-	r := l.input[l.pos]
-	l.pos++
+	r, w := utf8.DecodeRuneInString(l.input[l.pos:])
+	l.width = Pos(w)
+	l.pos += l.width
 	if r == '\n' {
 		l.line++
 	}
-	return rune(r)
+	return r
 }
 
 // backup steps back one rune. Can only be called once per call of next.
 func (l *lexer) backup() {
-	l.pos--
+	l.pos -= l.width
 	// Correct newline count.
-	if l.input[l.pos] == '\n' {
+	if l.width == 1 && l.input[l.pos] == '\n' {
 		l.line--
 	}
 }
 
 // nextItem returns the next item from the input.
-// Called by the parser, not in the lexing goroutine.
+// Called by the parser, it runs the state machine until an item is emitted.
+// Once an itemEOF or itemError has been returned, it returns itemEOF.
 func (l *lexer) nextItem() item {
-	return <-l.items
+	for l.head == len(l.items) {
+		if l.state == nil {
+			return item{typ: itemEOF, pos: l.pos, end: l.pos, line: l.line}
+		}
+		l.items, l.head = l.items[:0], 0
+		l.state = l.state(l)
+	}
+	i := l.items[l.head]
+	l.head++
+	return i
 }
 
 // peek returns but does not consume the next rune in the input.
@@ -125,13 +198,15 @@ func (l *lexer) isNextString(s string) bool {
 	return l.input[l.pos:wantPos] == s
 }
 
+// writeRune writes r to the buffer of the pending item.
 func (l *lexer) writeRune(r rune) {
 	l.buf.WriteRune(r)
-	l.ignore()
 }
 
-func (l *lexer) emitBuffer(t itemType) {
-	l.items <- item{t, l.start, l.buf.String(), l.startLine}
+// emitBuffer passes an item with the contents of the buffer as its value
+// back to the client. The style records how the value was written.
+func (l *lexer) emitBuffer(t itemType, style valueStyle) {
+	l.items = append(l.items, item{t, l.start, l.pos, l.buf.String(), l.startLine, style})
 	l.start = l.pos
 	l.startLine = l.line
 	l.buf.Reset()
@@ -139,15 +214,22 @@ func (l *lexer) emitBuffer(t itemType) {
 
 // emit passes an item back to the client.
 func (l *lexer) emit(t itemType) {
-	l.items <- item{t, l.start, l.input[l.start:l.pos], l.startLine}
+	l.emitStyle(t, styleNone)
+}
+
+// emitStyle passes an item written in the given style back to the client.
+func (l *lexer) emitStyle(t itemType, style valueStyle) {
+	l.items = append(l.items, item{t, l.start, l.pos, l.input[l.start:l.pos], l.startLine, style})
 	l.start = l.pos
 	l.startLine = l.line
 }
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
+// The error is reported at the current position, so the offending rune
+// must not have been consumed.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.items <- item{itemError, l.start, fmt.Sprintf(format, args...), l.startLine}
+	l.items = append(l.items, item{itemError, l.pos, l.pos, fmt.Sprintf(format, args...), l.line, styleNone})
 	return nil
 }
 
@@ -158,17 +240,29 @@ func (l *lexer) ignore() {
 	l.startLine = l.line
 }
 
-func (l *lexer) skip() {
-	l.next()
-	l.ignore()
+// inInlineTable reports whether the innermost value being scanned is an inline table.
+func (l *lexer) inInlineTable() bool {
+	return len(l.nest) > 0 && l.nest[len(l.nest)-1].typ == itemInlineTableStart
 }
 
-// skipN skips next n characters. use this when want to skip string.
-func (l *lexer) skipN(n int) {
-	l.pos += Pos(n)
+// trivia passes the pending input back to the client as an item of type t
+// if the lexer emits trivia, otherwise it skips over it.
+func (l *lexer) trivia(t itemType) {
+	if l.options.emitTrivia && l.pos > l.start {
+		l.emit(t)
+		return
+	}
 	l.ignore()
 }
 
+// skipSpace skips over any whitespace at the current position.
+func (l *lexer) skipSpace() {
+	for isSpace(l.peek()) {
+		l.next()
+	}
+	l.trivia(itemWhitespace)
+}
+
 // isSpace reports whether r is a space character.
 func isSpace(r rune) bool {
 	// https://github.com/toml-lang/toml#spec
@@ -176,10 +270,51 @@ func isSpace(r rune) bool {
 	return r == 0x09 || r == 0x20
 }
 
+// isControl reports whether r is a control character other than tab,
+// which may not appear unescaped in comments and strings
+// (U+0000 to U+0008, U+000A to U+001F, U+007F).
+func isControl(r rune) bool {
+	return 0x00 <= r && r <= 0x08 || 0x0a <= r && r <= 0x1f || r == 0x7f
+}
+
+// isNewline reports whether the input at the current position starts with
+// a newline, LF or CRLF.
+func (l *lexer) isNewline() bool {
+	return l.peek() == '\n' || l.isNextString("\r\n")
+}
+
+// lexNewline scans a newline, LF or CRLF, at the current position.
+func (l *lexer) lexNewline() {
+	l.skipNewline()
+	l.trivia(itemNewline)
+}
+
+// skipNewline skips a newline, LF or CRLF, if the input starts with one.
+func (l *lexer) skipNewline() {
+	if l.isNextString("\r\n") {
+		l.next()
+	}
+	if l.peek() == '\n' {
+		l.next()
+	}
+}
+
 func (l *lexer) isEOF() bool {
 	return int(l.pos) >= len(l.input)
 }
 
+// lexInvalidUTF8 reports the first byte of the input that is not valid UTF-8.
+func lexInvalidUTF8(l *lexer) stateFn {
+	for !l.isEOF() {
+		if r := l.next(); r == utf8.RuneError && l.width == 1 {
+			l.backup()
+			l.ignore()
+			return l.errorf("invalid UTF-8 byte: `%#02x`", l.input[l.pos])
+		}
+	}
+	return nil
+}
+
 func lexText(l *lexer) stateFn {
 	for {
 		next := l.peek()
@@ -191,14 +326,21 @@ func lexText(l *lexer) stateFn {
 			return lexComment(l)
 		case '=':
 			return lexEqual
-		case '\r', '\n':
+		case '[':
+			return lexLeftBracket
+		case ']':
+			return lexRightBracket
+		case '\n', '\r':
 			// https://github.com/toml-lang/toml#spec
 			// Newline means LF (0x0A) or CRLF (0x0D 0x0A).
-			l.next()
+			if l.isNewline() {
+				l.lexNewline()
+				continue
+			}
 		}
 
 		if isSpace(next) {
-			l.skip()
+			l.skipSpace()
 			continue
 		}
 
@@ -213,16 +355,70 @@ func lexText(l *lexer) stateFn {
 }
 
 func lexComment(l *lexer) stateFn {
-	for {
-		c := l.next()
-		if 0x00 <= c && c <= 0x08 || 0x0a <= c && c <= 0x1f || c == 0x7f {
-			return l.errorf("unexpected control character in comment: `%c`", c)
-		}
-		if c == '\n' || c == eof {
-			break
+	if err := scanComment(l); err != nil {
+		return l.errorf("%s", err)
+	}
+	return lexText
+}
+
+// scanComment scans a comment up to the end of the line.
+// The newline is not part of the comment.
+func scanComment(l *lexer) error {
+	for c := l.peek(); c != eof && !l.isNewline(); c = l.peek() {
+		if isControl(c) {
+			return fmt.Errorf("unexpected control character in comment: `%#U`", c)
 		}
+		l.next()
+	}
+	l.trivia(itemComment)
+	return nil
+}
+
+// lexLeftBracket scans the opening of a table header "[" or
+// an array of tables header "[[".
+// https://github.com/toml-lang/toml#table
+func lexLeftBracket(l *lexer) stateFn {
+	l.header = true
+	if l.follow("[[") {
+		l.emit(itemDoubleLeftBracket)
+		return lexHeaderKey
+	}
+	l.next()
+	l.emit(itemLeftBracket)
+	return lexHeaderKey
+}
+
+// lexHeaderKey scans the start of the key of a table header, which must be
+// on the same line as the bracket.
+func lexHeaderKey(l *lexer) stateFn {
+	l.skipSpace()
+	if c := l.peek(); !isKey(c) {
+		return l.errorf("expected key in table header: %s", describeRune(c))
+	}
+	return lexKey
+}
+
+// lexRightBracket scans the closing of a table header "]" or
+// an array of tables header "]]".
+func lexRightBracket(l *lexer) stateFn {
+	l.header = false
+	if l.follow("]]") {
+		l.emit(itemDoubleRightBracket)
+	} else {
+		l.next()
+		l.emit(itemRightBracket)
+	}
+	return lexLineEnd(l, "table header")
+}
+
+// lexLineEnd scans after a table header or a key/value pair, which must be
+// followed by the end of the line, a comment or the end of the input.
+// https://github.com/toml-lang/toml#spec
+func lexLineEnd(l *lexer, after string) stateFn {
+	l.skipSpace()
+	if c := l.peek(); c != eof && c != '#' && !l.isNewline() {
+		return l.errorf("expected newline after %s: `%#U`", after, c)
 	}
-	l.ignore()
 	return lexText
 }
 
@@ -233,300 +429,731 @@ func lexEqual(l *lexer) stateFn {
 }
 
 func lexValue(l *lexer) stateFn {
-	for {
-		c := l.next()
-		if c == eof {
-			return l.errorf("invalid unspecified value")
-		}
+	l.skipSpace()
+	c := l.next()
+	if c == eof {
+		return l.errorf("invalid unspecified value")
+	}
 
-		if isSpace(c) {
-			l.ignore()
-			continue
-		}
-		switch c {
-		case '"':
-			// check multiline first
-			if l.isNextString(`""`) {
-				l.skipN(2)
-				if err := scanMultiLineBasicStrings(l); err != nil {
-					return l.errorf(err.Error())
-				}
-				l.emitBuffer(itemStringValue)
-				break
+	switch c {
+	case '"':
+		// check multiline first
+		if l.follow(`""`) {
+			if err := scanMultiLineBasicStrings(l); err != nil {
+				return l.errorf("%s", err)
 			}
-			if err := scanBasicString(l); err != nil {
-				return l.errorf(err.Error())
+			l.emitBuffer(itemStringValue, styleMultiLineBasic)
+			return lexValueEnd
+		}
+		if err := scanBasicString(l); err != nil {
+			return l.errorf("%s", err)
+		}
+		l.emitBuffer(itemStringValue, styleBasic)
+		return lexValueEnd
+	case '\'':
+		if l.follow(`''`) {
+			if err := scanMultiLineLiteralStrings(l); err != nil {
+				return l.errorf("%s", err)
 			}
-			l.emitBuffer(itemStringValue)
-			l.skip()
-		case '\'':
-			if l.isNextString(`''`) {
-				l.skipN(2)
-				if err := scanMultiLineLiteralStrings(l); err != nil {
-					return l.errorf(err.Error())
-				}
-				l.emitBuffer(itemStringValue)
-				break
+			l.emitBuffer(itemStringValue, styleMultiLineLiteral)
+			return lexValueEnd
+		}
+		if err := scanLiteralString(l); err != nil {
+			return l.errorf("%s", err)
+		}
+		l.emitBuffer(itemStringValue, styleLiteral)
+		return lexValueEnd
+	case '+', '-', 'i', 'n':
+		// 'i' => expected "inf"
+		// 'n' => expected "nan"
+		return lexNumber(l, c)
+	case '[':
+		l.emit(itemArrayStart)
+		l.nest = append(l.nest, nest{typ: itemArrayStart})
+		return lexInsideArray
+	case '{':
+		l.emit(itemInlineTableStart)
+		l.nest = append(l.nest, nest{typ: itemInlineTableStart})
+		return lexInsideInlineTable
+	case 't', 'f':
+		if c == 't' && l.follow("rue") || c == 'f' && l.follow("alse") {
+			l.emit(itemBooleanValue)
+			return lexValueEnd
+		}
+		l.backup()
+		return l.errorf("expected `true` or `false`")
+	case '.':
+		if isDigit(l.peek()) {
+			l.backup()
+			return l.errorf("expected digit before decimal point")
+		}
+	}
+
+	if isDigit(c) {
+		if l.isDateTime() {
+			return lexDateTime
+		}
+		return lexNumber(l, c)
+	}
+
+	l.backup()
+	return l.errorf("invalid character in value: `%#U`", c)
+}
+
+// lexValueEnd scans after a value. Inside an array or an inline table
+// scanning continues with the next element of it.
+func lexValueEnd(l *lexer) stateFn {
+	if len(l.nest) == 0 {
+		return lexLineEnd(l, "value")
+	}
+	n := &l.nest[len(l.nest)-1]
+	n.afterValue, n.afterComma = true, false
+	if n.typ == itemArrayStart {
+		return lexInsideArray
+	}
+	return lexInsideInlineTable
+}
+
+// lexInsideArray scans the elements of an array. Arrays can span
+// multiple lines, and may contain comments and a terminating comma.
+// https://github.com/toml-lang/toml#array
+func lexInsideArray(l *lexer) stateFn {
+	n := &l.nest[len(l.nest)-1]
+	for {
+		c := l.peek()
+		switch {
+		case c == eof:
+			return l.errorf("unterminated array")
+		case isSpace(c):
+			l.skipSpace()
+		case l.isNewline():
+			l.lexNewline()
+		case c == '\r':
+			return l.errorf("invalid character: `%#U`", c)
+		case c == '#':
+			if err := scanComment(l); err != nil {
+				return l.errorf("%s", err)
 			}
-			l.ignore()
-			// check multiline first
-			if err := scanLiteralString(l); err != nil {
-				return l.errorf(err.Error())
+		case c == ',':
+			if !n.afterValue {
+				return l.errorf("unexpected `,` in array")
 			}
-			l.emitBuffer(itemStringValue)
-			l.skip() // skip "'" at last
-		case '+', '-', 'i', 'n':
-			// 'i' => expected "inf"
-			// 'n' => expected "nan"
-			return lexNumber(l, c)
-		case 't', 'f':
-			if l.follow("rue") || l.follow("alse") {
-				l.emit(itemBooleanValue)
-				return lexText
+			l.next()
+			l.emit(itemComma)
+			n.afterValue, n.afterComma = false, true
+		case c == ']':
+			l.next()
+			l.emit(itemArrayEnd)
+			l.nest = l.nest[:len(l.nest)-1]
+			return lexValueEnd
+		default:
+			if n.afterValue {
+				return l.errorf("expected `,` or `]` after array element: `%#U`", c)
 			}
+			return lexValue
 		}
+	}
+}
 
-		if isDigit(c) {
-			return lexNumber(l, c)
+// lexInsideInlineTable scans the key/value pairs of an inline table.
+// Inline tables must appear on a single line and
+// a terminating comma is not permitted.
+// https://github.com/toml-lang/toml#inline-table
+func lexInsideInlineTable(l *lexer) stateFn {
+	n := &l.nest[len(l.nest)-1]
+	for {
+		c := l.peek()
+		switch {
+		case c == eof:
+			return l.errorf("unterminated inline table")
+		case isSpace(c):
+			l.skipSpace()
+		case c == '\r' || c == '\n':
+			return l.errorf("newline is not allowed in inline table")
+		case c == ',':
+			if !n.afterValue {
+				return l.errorf("unexpected `,` in inline table")
+			}
+			l.next()
+			l.emit(itemComma)
+			n.afterValue, n.afterComma = false, true
+		case c == '}':
+			if n.afterComma {
+				return l.errorf("trailing comma is not allowed in inline table")
+			}
+			l.next()
+			l.emit(itemInlineTableEnd)
+			l.nest = l.nest[:len(l.nest)-1]
+			return lexValueEnd
+		case isKey(c):
+			if n.afterValue {
+				return l.errorf("expected `,` or `}` after key/value pair in inline table: `%#U`", c)
+			}
+			return lexKey
+		default:
+			return l.errorf("invalid character in inline table: `%#U`", c)
 		}
-
-		return lexText
 	}
 }
 
 func lexNumber(l *lexer, head rune) stateFn {
-	if head == '+' || head == '-' {
+	signed := head == '+' || head == '-'
+	if signed {
 		head = l.next()
+		if head == '.' {
+			l.backup()
+			return l.errorf("expected digit before decimal point")
+		}
 		if head != 'i' && head != 'n' && !isDigit(head) {
-			return l.errorf("expected digit character: `%#U`", head)
+			l.backup()
+			return l.errorf("expected digit character: %s", describeRune(head))
 		}
 	}
 
 	// "inf" or "nan"
-	if l.follow("nf") || l.follow("an") {
+	if head == 'i' && l.follow("nf") || head == 'n' && l.follow("an") {
 		l.emit(itemFloatValue)
-		return lexText
+		return lexValueEnd
+	}
+	if !isDigit(head) {
+		l.backup()
+		return l.errorf("expected `inf` or `nan`")
 	}
 
+	// A hexadecimal, octal or binary integer.
+	// https://github.com/toml-lang/toml#integer
+	//
+	//	hex-int = hex-prefix HEXDIG *( HEXDIG / underscore HEXDIG )
+	//	oct-int = oct-prefix digit0-7 *( digit0-7 / underscore digit0-7 )
+	//	bin-int = bin-prefix digit0-1 *( digit0-1 / underscore digit0-1 )
 	if head == '0' {
-		c := l.peek()
-		switch c {
-		case 'x':
-			l.next()
-			if err := scanHex(l); err != nil {
-				return l.errorf(err.Error())
+		switch c := l.peek(); c {
+		case 'x', 'o', 'b':
+			if signed {
+				l.pos = l.start
+				return l.errorf("a sign is not allowed on %s integers", baseNames[c])
 			}
-			l.emit(itemIntegerValue)
-			return lexText
-		case 'o':
 			l.next()
-			if err := scanOct(l); err != nil {
-				return l.errorf(err.Error())
-			}
-			l.emit(itemIntegerValue)
-			return lexText
-		case 'b':
+			return lexPrefixedInteger(l, c)
+		case 'X', 'O', 'B':
+			return l.errorf("a base prefix must be lowercase: `0%c`", c)
+		}
+	}
+
+	// A decimal integer or a float.
+	// https://github.com/toml-lang/toml#float
+	//
+	//	float = float-int-part ( exp / frac [ exp ] )
+	//	float-int-part = dec-int
+	//	frac = decimal-point zero-prefixable-int
+	//	exp = "e" float-exp-part
+	//	float-exp-part = [ minus / plus ] zero-prefixable-int
+	intPart := l.pos - 1
+	leadingZero := head == '0' && (isDigit(l.peek()) || l.peek() == '_')
+	if err := scanDigits(l); err != nil {
+		return l.errorf("%s", err)
+	}
+	isFloat, hasExp := false, false
+	if l.peek() == '.' {
+		l.next()
+		if c := l.peek(); !isDigit(c) {
+			return l.errorf("expected digit after decimal point: %s", describeRune(c))
+		}
+		if err := scanDigits(l); err != nil {
+			return l.errorf("%s", err)
+		}
+		isFloat = true
+	}
+	if c := l.peek(); c == 'e' || c == 'E' {
+		l.next()
+		if c := l.peek(); c == '+' || c == '-' {
 			l.next()
-			if err := scanBin(l); err != nil {
-				return l.errorf(err.Error())
-			}
-			l.emit(itemIntegerValue)
-			return lexText
 		}
+		if c := l.peek(); !isDigit(c) {
+			return l.errorf("expected digit in exponent: %s", describeRune(c))
+		}
+		if err := scanDigits(l); err != nil {
+			return l.errorf("%s", err)
+		}
+		isFloat, hasExp = true, true
+	}
+	switch c := l.peek(); {
+	case c == '.' && hasExp:
+		return l.errorf("unexpected decimal point in exponent")
+	case c == '.':
+		return l.errorf("unexpected second decimal point in float")
+	case (c == 'e' || c == 'E') && hasExp:
+		return l.errorf("unexpected second exponent in float")
+	case c == '+' || c == '-':
+		return l.errorf("unexpected sign %s, an exponent must start with `e`", describeRune(c))
+	}
+	if leadingZero {
+		l.pos = intPart
+		if isFloat {
+			return l.errorf("leading zeros are not allowed in the integer part of a float")
+		}
+		return l.errorf("leading zeros are not allowed in a decimal integer")
 	}
+	if !isFloat {
+		l.emitStyle(itemIntegerValue, styleDecimal)
+		return lexValueEnd
+	}
+	l.emit(itemFloatValue)
+	return lexValueEnd
+}
 
-	// lexing decimal or float
-	// float: https://github.com/toml-lang/toml#float
-	founddot := false
-	founde := false
-	foundPlusMinus := false
+// baseNames are the names of the bases of integers by their prefix letter.
+var baseNames = map[rune]string{
+	'x': "hexadecimal",
+	'o': "octal",
+	'b': "binary",
+}
 
-	for {
-		c := l.next()
-		if isDigit(c) {
-			continue
-		}
+// lexPrefixedInteger scans a hexadecimal, octal or binary integer after
+// its prefix 0x, 0o or 0b.
+func lexPrefixedInteger(l *lexer, prefix rune) stateFn {
+	var (
+		isBaseDigit func(rune) bool
+		style       valueStyle
+	)
+	switch prefix {
+	case 'x':
+		isBaseDigit, style = isHex, styleHex
+	case 'o':
+		isBaseDigit, style = isOct, styleOctal
+	case 'b':
+		isBaseDigit, style = isBin, styleBinary
+	}
+	if c := l.peek(); !isBaseDigit(c) {
+		return l.errorf("expected %s digit after `0%c`: %s", baseNames[prefix], prefix, describeRune(c))
+	}
+	if err := scanInteger(l, isBaseDigit); err != nil {
+		return l.errorf("%s", err)
+	}
+	if c := l.peek(); isHex(c) || c == '.' {
+		return l.errorf("invalid %s digit: %s", baseNames[prefix], describeRune(c))
+	}
+	l.emitStyle(itemIntegerValue, style)
+	return lexValueEnd
+}
 
-		if !founddot && c == '.' {
-			founddot = true
-			continue
-		}
+// describeRune returns r quoted for an error message, or "EOF".
+func describeRune(r rune) string {
+	if r == eof {
+		return "EOF"
+	}
+	return fmt.Sprintf("`%#U`", r)
+}
 
-		if !founde && (c == 'e' || c == 'E') {
-			founde = true
-			continue
-		}
+// isDateTime reports whether the pending input starts like a date ("1979-")
+// or a time ("07:"), neither of which can be the start of a number.
+func (l *lexer) isDateTime() bool {
+	s := l.input[l.start:]
+	return len(s) > 4 && isDigits(s[:4]) && s[4] == '-' ||
+		len(s) > 2 && isDigits(s[:2]) && s[2] == ':'
+}
 
-		// case of "6.626e-34"
-		if founde && !foundPlusMinus && (c == '-' || c == '+') {
-			foundPlusMinus = true
-			continue
+// lexDateTime scans an offset date-time, local date-time, local date or
+// local time. These are RFC 3339 formatted.
+// https://github.com/toml-lang/toml#offset-date-time
+func lexDateTime(l *lexer) stateFn {
+	l.pos = l.start // rewind the first digit consumed by lexValue
+	s := l.input[l.start:]
+	hasDate := len(s) > 4 && isDigits(s[:4]) && s[4] == '-'
+	if hasDate {
+		if err := scanDate(l); err != nil {
+			return l.errorf("%s", err)
+		}
+		// The delimiter may be replaced with a space character.
+		// In that case a time must follow, otherwise it is a local date.
+		c := l.peek()
+		isDelim := c == 'T' || c == 't' ||
+			c == ' ' && int(l.pos)+3 < len(l.input) && l.input[l.pos+3] == ':' && isDigits(l.input[l.pos+1:l.pos+3])
+		if !isDelim {
+			l.emit(itemTimeValue)
+			return lexValueEnd
+		}
+		l.next()
+	}
+	if err := scanTime(l); err != nil {
+		return l.errorf("%s", err)
+	}
+	switch c := l.peek(); c {
+	case 'Z', 'z', '+', '-':
+		if !hasDate {
+			return l.errorf("unexpected offset on local time: `%#U`", c)
+		}
+		if err := scanOffset(l); err != nil {
+			return l.errorf("%s", err)
 		}
+	}
+	l.emit(itemTimeValue)
+	return lexValueEnd
+}
 
-		if c == '_' {
-			if isDigit(l.peek()) {
-				continue
-			}
-			return l.errorf("expected integer after '_'")
+// scanDate scans full-date (YYYY-MM-DD).
+func scanDate(l *lexer) error {
+	year, err := scanFixedDigits(l, 4, "year")
+	if err != nil {
+		return err
+	}
+	if err := scanDelim(l, '-', "date"); err != nil {
+		return err
+	}
+	month, err := scanFixedDigits(l, 2, "month")
+	if err != nil {
+		return err
+	}
+	if month < 1 || 12 < month {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("month out of range: %02d", month)
+	}
+	if err := scanDelim(l, '-', "date"); err != nil {
+		return err
+	}
+	day, err := scanFixedDigits(l, 2, "day")
+	if err != nil {
+		return err
+	}
+	if day < 1 || daysIn(month, year) < day {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("day out of range: %04d-%02d-%02d", year, month, day)
+	}
+	return nil
+}
+
+// scanTime scans partial-time (HH:MM:SS with optional fractional seconds).
+func scanTime(l *lexer) error {
+	hour, err := scanFixedDigits(l, 2, "hour")
+	if err != nil {
+		return err
+	}
+	if 23 < hour {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("hour out of range: %02d", hour)
+	}
+	if err := scanDelim(l, ':', "time"); err != nil {
+		return err
+	}
+	minute, err := scanFixedDigits(l, 2, "minute")
+	if err != nil {
+		return err
+	}
+	if 59 < minute {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("minute out of range: %02d", minute)
+	}
+	if err := scanDelim(l, ':', "time"); err != nil {
+		return err
+	}
+	second, err := scanFixedDigits(l, 2, "second")
+	if err != nil {
+		return err
+	}
+	if 59 < second {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("second out of range: %02d", second)
+	}
+	if l.peek() != '.' {
+		return nil
+	}
+	l.next()
+	if c := l.peek(); !isDigit(c) {
+		if c == eof {
+			return fmt.Errorf("unexpected EOF in time")
 		}
+		return fmt.Errorf("expected digit after decimal point in time: `%#U`", c)
+	}
+	for isDigit(l.peek()) {
+		l.next()
+	}
+	return nil
+}
 
-		break
+// scanOffset scans time-offset ("Z" or +HH:MM / -HH:MM).
+func scanOffset(l *lexer) error {
+	switch l.next() {
+	case 'Z', 'z':
+		return nil
+	}
+	hour, err := scanFixedDigits(l, 2, "offset hour")
+	if err != nil {
+		return err
+	}
+	if 23 < hour {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("offset hour out of range: %02d", hour)
+	}
+	if err := scanDelim(l, ':', "offset"); err != nil {
+		return err
+	}
+	minute, err := scanFixedDigits(l, 2, "offset minute")
+	if err != nil {
+		return err
 	}
+	if 59 < minute {
+		l.pos -= 2 // report the error at the first digit
+		return fmt.Errorf("offset minute out of range: %02d", minute)
+	}
+	return nil
+}
 
-	if founddot || founde {
-		l.emit(itemFloatValue)
-		return lexText
+// scanFixedDigits scans exactly n decimal digits and returns their value.
+func scanFixedDigits(l *lexer, n int, name string) (int, error) {
+	v := 0
+	for i := 0; i < n; i++ {
+		c := l.next()
+		if c == eof {
+			return 0, fmt.Errorf("unexpected EOF in %s", name)
+		}
+		if !isDigit(c) {
+			l.backup()
+			return 0, fmt.Errorf("expected %d digit %s: `%#U`", n, name, c)
+		}
+		v = v*10 + int(c-'0')
 	}
+	return v, nil
+}
 
-	l.emit(itemIntegerValue)
-	return lexText
+func scanDelim(l *lexer, delim rune, name string) error {
+	c := l.next()
+	if c == eof {
+		return fmt.Errorf("unexpected EOF in %s", name)
+	}
+	if c != delim {
+		l.backup()
+		return fmt.Errorf("expected `%c` in %s: `%#U`", delim, name, c)
+	}
+	return nil
+}
+
+// daysIn returns the number of days in month of year.
+func daysIn(month, year int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
 }
 
 // https://github.com/toml-lang/toml#keys
 func isKey(c rune) bool {
+	isQuoted := c == '"' || c == '\''
+	return isBareKey(c) || isQuoted
+}
+
+// isBareKey reports whether c may be used in a bare key.
+// Bare keys may only contain ASCII letters, ASCII digits, underscores, and dashes.
+func isBareKey(c rune) bool {
 	isDigit := '0' <= c && c <= '9'
 	isLetters := 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
 	isDash := c == '-'
 	isUnderscore := c == '_'
-	isQuoted := c == '"' || c == '\''
-	return isDigit || isLetters || isDash || isUnderscore || isQuoted
+	return isDigit || isLetters || isDash || isUnderscore
 }
 
 func lexKey(l *lexer) stateFn {
-	for {
-		c := l.next()
-		if !isKey(c) && c != '\r' && c != '\n' {
-			l.backup()
-			l.emit(itemKey)
-			return lexText
+	c := l.next()
+	switch c {
+	case '"', '\'':
+		return lexQuotedKey(l, c)
+	}
+	for isBareKey(l.peek()) {
+		l.next()
+	}
+	l.emit(itemKey)
+	return lexKeyEnd
+}
+
+// lexKeyEnd scans after a key. If a dot follows, the key is a dotted key and
+// the next part is scanned. Whitespace around dot-separated parts is ignored.
+// https://github.com/toml-lang/toml#keys
+func lexKeyEnd(l *lexer) stateFn {
+	l.skipSpace()
+	if c := l.peek(); c != '.' {
+		switch {
+		case l.inInlineTable():
+			if c != '=' {
+				return l.errorf("expected `=` after key in inline table: %s", describeRune(c))
+			}
+			return lexEqual
+		case l.header:
+			if c != ']' {
+				return l.errorf("expected `]` after key in table header: %s", describeRune(c))
+			}
+			return lexRightBracket
 		}
-		switch c {
-		case '"', '\'':
-			return lexQuotedKey(l, c)
+		if c != '=' {
+			return l.errorf("expected `=` after key: %s", describeRune(c))
 		}
+		return lexEqual
 	}
+	l.next()
+	l.emit(itemDot)
+	l.skipSpace()
+	if c := l.peek(); !isKey(c) {
+		return l.errorf("expected key after `.`: %s", describeRune(c))
+	}
+	return lexKey
 }
 
 func lexQuotedKey(l *lexer, delim rune) stateFn {
-	l.ignore() // ignore delim
-
 	// if "Literal strings"
 	if delim == '\'' {
 		if err := scanLiteralString(l); err != nil {
-			return l.errorf(err.Error())
+			return l.errorf("%s", err)
 		}
-		l.emitBuffer(itemKey)
-		l.skip() // skip "'" at last
-		return lexText
+		l.emitBuffer(itemKey, styleLiteral)
+		return lexKeyEnd
 	}
 	// if "Basic strings"
 	if delim == '"' {
 		if err := scanBasicString(l); err != nil {
-			return l.errorf(err.Error())
+			return l.errorf("%s", err)
 		}
-		l.emitBuffer(itemKey)
-		l.skip()
-		return lexText
+		l.emitBuffer(itemKey, styleBasic)
+		return lexKeyEnd
 	}
 	return l.errorf("unsupported delimiter: `%c`", delim)
 }
 
+// scanLiteralString scans a literal string after its opening quote.
+// It must end on the same line and may contain any character but control
+// characters other than tab. There is no escaping.
+// https://github.com/toml-lang/toml#string
 func scanLiteralString(l *lexer) error {
 	for {
+		if l.peek() == eof || l.isNewline() {
+			return fmt.Errorf("unterminated literal string")
+		}
 		c := l.next()
-		switch c {
-		case eof, '\'':
+		if c == '\'' {
 			return nil
-		case '\t', '\r', '\n':
-		default:
-			l.writeRune(c)
 		}
+		if isControl(c) {
+			l.backup()
+			return fmt.Errorf("unexpected control character in literal string: `%#U`", c)
+		}
+		l.writeRune(c)
 	}
 }
 
+// scanBasicString scans a basic string after its opening quote.
+// It must end on the same line and may contain any character but
+// backslash and control characters other than tab, which must be escaped.
 func scanBasicString(l *lexer) error {
 	for {
+		if l.peek() == eof || l.isNewline() {
+			return fmt.Errorf("unterminated basic string")
+		}
 		c := l.next()
-
-		switch c {
-		case '"':
+		switch {
+		case c == '"':
 			return nil
-		case '\\':
-			l.ignore()
-			esc := l.next()
-			if err := scanEscapedChars(l, esc); err != nil {
+		case c == '\\':
+			if err := scanEscapedChars(l, l.next(), "basic string"); err != nil {
 				return err
 			}
+		case isControl(c):
+			l.backup()
+			return fmt.Errorf("unexpected control character in basic string: `%#U`", c)
 		default:
 			l.writeRune(c)
 		}
 	}
 }
 
+// scanMultiLineBasicStrings scans a multi-line basic string after its
+// opening delimiter. Newlines are allowed in addition to what is allowed
+// in a basic string, and a newline right after the opening delimiter
+// is trimmed.
 func scanMultiLineBasicStrings(l *lexer) error {
-	onHead := true
-
+	l.skipNewline()
 	for {
-		if l.isNextString(`"""`) {
-			l.skipN(3)
-			if l.peek() == '"' {
-				l.skipN(-2)
-				l.writeRune('"')
-				continue
-			}
-			return nil
-		}
-
-		c := l.next()
-		if c == eof {
-			return nil
+		if end, err := l.scanMultiLineEnd('"'); end || err != nil {
+			return err
 		}
-
-		// Any Unicode character may be used except those that
-		// must be escaped: backslash and the control characters
-		// other than tab, line feed, and carriage return
-		// (U+0000 to U+0008, U+000B, U+000C, U+000E to U+001F, U+007F).
-		if 0x00 <= c && c <= 0x08 || c == 0x0b || c == 0x0c || 0x0e <= c && c <= 0x1f || c == 0x7f {
-			return fmt.Errorf("unexpected control character: `%#U`", c)
-		}
-
-		// A newline immediately following the opening delimiter will be trimmed.
-		for onHead && (c == '\n' || c == '\r') {
-			c = l.next()
+		if l.isNewline() {
+			l.writeNewline()
+			continue
 		}
-		onHead = false
-
-		if c == '\\' {
-			l.ignore()
-			c = l.next()
 
+		c := l.next()
+		switch {
+		case c == eof:
+			return fmt.Errorf("unterminated multi-line basic string")
+		case c == '\\':
 			// When the last non-whitespace character on a line is an unescaped \,
 			// it will be trimmed along with all whitespace (including newlines)
 			// up to the next non-whitespace character or closing delimiter.
-			canSkip := false
-			for c == ' ' || c == '\n' || c == '\r' {
-				c = l.next()
-				canSkip = true
-			}
-			if canSkip {
-				// others: c == ' ' || c == '\n' || c == '\r'
-				l.backup()
-				l.ignore()
+			if isSpace(l.peek()) || l.isNewline() {
+				for isSpace(l.peek()) {
+					l.next()
+				}
+				if !l.isNewline() {
+					return fmt.Errorf("unexpected character after line ending backslash: %s", describeRune(l.peek()))
+				}
+				for isSpace(l.peek()) || l.isNewline() {
+					l.next()
+				}
 				continue
 			}
-
-			if err := scanEscapedChars(l, c); err != nil {
+			if err := scanEscapedChars(l, l.next(), "multi-line basic string"); err != nil {
 				return err
 			}
-			continue
+		case isControl(c):
+			l.backup()
+			return fmt.Errorf("unexpected control character in multi-line basic string: `%#U`", c)
+		default:
+			l.writeRune(c)
 		}
+	}
+}
 
-		l.writeRune(c)
+// scanMultiLineEnd reports whether the input starts with the closing
+// delimiter of a multi-line string quoted with q, and consumes it.
+// Up to two quotation marks may precede the closing delimiter,
+// they are written to the buffer.
+func (l *lexer) scanMultiLineEnd(q byte) (bool, error) {
+	n := 0
+	for int(l.pos)+n < len(l.input) && l.input[int(l.pos)+n] == q {
+		n++
+	}
+	switch {
+	case n < 3:
+		return false, nil
+	case n > 5:
+		l.pos += 5
+		return false, fmt.Errorf("too many quotation marks before the closing delimiter")
+	}
+	for i := 3; i < n; i++ {
+		l.writeRune(rune(q))
 	}
+	l.pos += Pos(n)
+	return true, nil
+}
+
+// writeNewline consumes a newline and writes it to the buffer as it is
+// in the input, LF or CRLF.
+func (l *lexer) writeNewline() {
+	if l.next() == '\r' {
+		l.writeRune('\r')
+		l.next()
+	}
+	l.writeRune('\n')
 }
 
-func scanEscapedChars(l *lexer, c rune) error {
+// scanEscapedChars scans the escape sequence starting with c after a
+// backslash in a string of the given kind, such as "basic string".
+func scanEscapedChars(l *lexer, c rune, kind string) error {
 	switch c {
+	case eof:
+		return fmt.Errorf("unterminated %s", kind)
 	case 'b':
 		l.writeRune('\b')
 	case 't':
@@ -545,77 +1172,84 @@ func scanEscapedChars(l *lexer, c rune) error {
 		var code string
 		for i := 0; i < 4; i++ {
 			cc := l.next()
+			if cc == eof {
+				return fmt.Errorf("unterminated %s", kind)
+			}
 			if !isHex(cc) {
-				return fmt.Errorf("unexpected character: `%#U`", cc)
+				l.backup()
+				return fmt.Errorf("expected hex digit in unicode escape: %s", describeRune(cc))
 			}
 			code += string(cc)
 		}
 		i, err := strconv.ParseInt(code, 16, 32)
-		if err != nil {
-			return fmt.Errorf("invalid unicode escape: `\\u%s`", code)
-		}
-		if !isUnicodeScalar(i) {
-			return fmt.Errorf("invalid unicode scalar: `\\u%s`", code)
+		if err != nil || !isUnicodeScalar(i) {
+			l.pos -= Pos(len(code) + 2) // report the error at the backslash
+			return fmt.Errorf("invalid unicode scalar: `\\%c%s`", c, code)
 		}
 		l.writeRune(rune(i))
 	case 'U':
 		var code string
 		for i := 0; i < 8; i++ {
 			cc := l.next()
+			if cc == eof {
+				return fmt.Errorf("unterminated %s", kind)
+			}
 			if !isHex(cc) {
-				return fmt.Errorf("unexpected character: `%#U`", cc)
+				l.backup()
+				return fmt.Errorf("expected hex digit in unicode escape: %s", describeRune(cc))
 			}
 			code += string(cc)
 		}
 		i, err := strconv.ParseInt(code, 16, 64)
-		if err != nil {
-			return fmt.Errorf("invalid unicode escape: `\\u%s`", code)
-		}
-		if !isUnicodeScalar(i) {
-			return fmt.Errorf("invalid unicode scalar: `\\u%s`", code)
+		if err != nil || !isUnicodeScalar(i) {
+			l.pos -= Pos(len(code) + 2) // report the error at the backslash
+			return fmt.Errorf("invalid unicode scalar: `\\%c%s`", c, code)
 		}
 		l.writeRune(rune(i))
 	default:
-		return fmt.Errorf("invalid escape: `%#U`", c)
+		l.backup()
+		return fmt.Errorf("invalid escape: %s", describeRune(c))
 	}
 	return nil
 }
 
+// scanMultiLineLiteralStrings scans a multi-line literal string after its
+// opening delimiter. Newlines are allowed in addition to what is allowed
+// in a literal string, and a newline right after the opening delimiter
+// is trimmed.
 func scanMultiLineLiteralStrings(l *lexer) error {
-	onHead := true
+	l.skipNewline()
 	for {
-		if l.isNextString(`'''`) {
-			l.skipN(3)
-			if l.peek() == '\'' {
-				l.skipN(-2)
-				l.writeRune('\'')
-				continue
-			}
-			return nil
+		if end, err := l.scanMultiLineEnd('\''); end || err != nil {
+			return err
+		}
+		if l.isNewline() {
+			l.writeNewline()
+			continue
 		}
 
 		c := l.next()
 		if c == eof {
-			return nil
+			return fmt.Errorf("unterminated multi-line literal string")
 		}
-		// A newline immediately following the opening delimiter will be trimmed.
-		for onHead && (c == '\n' || c == '\r') {
-			c = l.next()
+		if isControl(c) {
+			l.backup()
+			return fmt.Errorf("unexpected control character in multi-line literal string: `%#U`", c)
 		}
-		onHead = false
-
 		l.writeRune(c)
 	}
 }
 
 func scanInteger(l *lexer, cond func(rune) bool) error {
 	for {
-		c := l.next()
+		c := l.peek()
 		if cond(c) {
+			l.next()
 			continue
 		}
 
 		if c == '_' {
+			l.next()
 			if cond(l.peek()) {
 				continue
 			}
@@ -630,26 +1264,27 @@ func scanDigits(l *lexer) error {
 	return scanInteger(l, isDigit)
 }
 
-func scanHex(l *lexer) error {
-	return scanInteger(l, isHex)
-}
-
-func scanOct(l *lexer) error {
-	return scanInteger(l, func(c rune) bool {
-		return '0' <= c && c <= '7'
-	})
+func isOct(r rune) bool {
+	return '0' <= r && r <= '7'
 }
 
-func scanBin(l *lexer) error {
-	return scanInteger(l, func(c rune) bool {
-		return c == '0' || c == '1'
-	})
+func isBin(r rune) bool {
+	return r == '0' || r == '1'
 }
 
 func isDigit(r rune) bool {
 	return '0' <= r && r <= '9'
 }
 
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(rune(s[i])) {
+			return false
+		}
+	}
+	return true
+}
+
 func isHex(r rune) bool {
 	return isDigit(r) || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F'
 }
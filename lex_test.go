@@ -1,6 +1,7 @@
 package toml
 
 import (
+	"runtime"
 	"testing"
 )
 
@@ -26,28 +27,40 @@ func TestLex(t *testing.T) {
 		{"empty", "", []item{tEOF}},
 		{"comment", "# hello, world", []item{tEOF}},
 		{"spaces", "    \t", []item{tEOF}},
-		{"literal string quoted key", "'key'", []item{
+		{"literal string quoted key", "'key' = 1", []item{
 			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
-		{"basic string", `"key"`, []item{
+		{"basic string", `"key" = 1`, []item{
 			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
-		{"basic string with \\b\\t\\n\\f\\r\\\"\\\\", `"hello\b\t\n\f\r\"\\world"`, []item{
+		{"basic string with \\b\\t\\n\\f\\r\\\"\\\\", `"hello\b\t\n\f\r\"\\world" = 1`, []item{
 			mkItem(itemKey, "hello\b\t\n\f\r\"\\world"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
-		{"basic string with unicode 4-digits", `"\u65E5\u672C\u8A9E"`, []item{
+		{"basic string with unicode 4-digits", `"\u65E5\u672C\u8A9E" = 1`, []item{
 			mkItem(itemKey, "日本語"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
-		{"basic string with unicode 8-digits", `"\U000065e5\U0000672c\U00008a9e"`, []item{
+		{"basic string with unicode 8-digits", `"\U000065e5\U0000672c\U00008a9e" = 1`, []item{
 			mkItem(itemKey, "日本語"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
-		{"basic string complex", `"I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."`, []item{
+		{"basic string complex", `"I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF." = 1`, []item{
 			mkItem(itemKey, "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
 			tEOF,
 		}},
 		{"invalid key", "key =", []item{
@@ -73,6 +86,24 @@ func TestLex(t *testing.T) {
 			mkItem(itemStringValue, "hello"),
 			tEOF,
 		}},
+		{"invalid bare carriage return", "a = 1\r\n\rb = 2", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemError, "invalid character: `U+000D`"),
+		}},
+		{"key = literal string with tab", "key = 'a\tb'", []item{
+			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "a\tb"),
+			tEOF,
+		}},
+		{"key = basic string with tab", "key = \"a\tb\"", []item{
+			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "a\tb"),
+			tEOF,
+		}},
 		{"key = multi-line basic string strings", "key = \"\"\"\nhello\nworld\"\"\"", []item{
 			mkItem(itemKey, "key"),
 			mkItem(itemEqual, "="),
@@ -128,7 +159,7 @@ func TestLex(t *testing.T) {
 				mkItem(itemKey, "key"),
 				mkItem(itemEqual, "="),
 				mkItem(itemStringValue, "Here are three quotation marks: "),
-				mkItem(itemError, "invalid character: `U+002E '.'`"),
+				mkItem(itemError, "expected newline after value: `U+002E '.'`"),
 			},
 		},
 		{
@@ -147,7 +178,7 @@ func TestLex(t *testing.T) {
 			[]item{
 				mkItem(itemKey, "key"),
 				mkItem(itemEqual, "="),
-				mkItem(itemStringValue, "hello\nworld\n"),
+				mkItem(itemStringValue, "\nhello\nworld\n"),
 				tEOF,
 			},
 		},
@@ -181,11 +212,10 @@ func TestLex(t *testing.T) {
 				tEOF,
 			},
 		},
-		{"key = integer", `key = 01234`, []item{
+		{"invalid key = integer with leading zero", `key = 01234`, []item{
 			mkItem(itemKey, "key"),
 			mkItem(itemEqual, "="),
-			mkItem(itemIntegerValue, "01234"),
-			tEOF,
+			mkItem(itemError, "leading zeros are not allowed in a decimal integer"),
 		}},
 		{"key = integer 0", `key = 0`, []item{
 			mkItem(itemKey, "key"),
@@ -244,11 +274,10 @@ func TestLex(t *testing.T) {
 			mkItem(itemEqual, "="),
 			mkItem(itemError, "expected integer after '_'"),
 		}},
-		{"key = integer oct", `oct1 = -0o01234567`, []item{
+		{"invalid key = integer oct with sign", `oct1 = -0o01234567`, []item{
 			mkItem(itemKey, "oct1"),
 			mkItem(itemEqual, "="),
-			mkItem(itemIntegerValue, "-0o01234567"),
-			tEOF,
+			mkItem(itemError, "a sign is not allowed on octal integers"),
 		}},
 		{"key = integer bin", `bin1 = 0b11010110`, []item{
 			mkItem(itemKey, "bin1"),
@@ -357,6 +386,473 @@ func TestLex(t *testing.T) {
 			mkItem(itemBooleanValue, "false"),
 			tEOF,
 		}},
+		{"offset date-time", `odt1 = 1979-05-27T07:32:00Z`, []item{
+			mkItem(itemKey, "odt1"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27T07:32:00Z"),
+			tEOF,
+		}},
+		{"offset date-time with offset", `odt2 = 1979-05-27T00:32:00-07:00`, []item{
+			mkItem(itemKey, "odt2"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27T00:32:00-07:00"),
+			tEOF,
+		}},
+		{"offset date-time with fraction", `odt3 = 1979-05-27T00:32:00.999999+07:00`, []item{
+			mkItem(itemKey, "odt3"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27T00:32:00.999999+07:00"),
+			tEOF,
+		}},
+		{"offset date-time with space", `odt4 = 1979-05-27 07:32:00Z`, []item{
+			mkItem(itemKey, "odt4"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27 07:32:00Z"),
+			tEOF,
+		}},
+		{"offset date-time lower case", `odt5 = 1979-05-27t07:32:00z`, []item{
+			mkItem(itemKey, "odt5"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27t07:32:00z"),
+			tEOF,
+		}},
+		{"local date-time", `ldt1 = 1979-05-27T07:32:00`, []item{
+			mkItem(itemKey, "ldt1"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27T07:32:00"),
+			tEOF,
+		}},
+		{"local date-time with fraction", `ldt2 = 1979-05-27T00:32:00.999999`, []item{
+			mkItem(itemKey, "ldt2"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27T00:32:00.999999"),
+			tEOF,
+		}},
+		{"local date", `ld1 = 1979-05-27`, []item{
+			mkItem(itemKey, "ld1"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27"),
+			tEOF,
+		}},
+		{"local date leap year", `ld2 = 2000-02-29`, []item{
+			mkItem(itemKey, "ld2"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "2000-02-29"),
+			tEOF,
+		}},
+		{"local date with trailing space", `ld3 = 1979-05-27 `, []item{
+			mkItem(itemKey, "ld3"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27"),
+			tEOF,
+		}},
+		{"local time", `lt1 = 07:32:00`, []item{
+			mkItem(itemKey, "lt1"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "07:32:00"),
+			tEOF,
+		}},
+		{"local time with fraction", `lt2 = 00:32:00.999999`, []item{
+			mkItem(itemKey, "lt2"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "00:32:00.999999"),
+			tEOF,
+		}},
+		{"local date with comment", "ld = 1979-05-27 # comment", []item{
+			mkItem(itemKey, "ld"),
+			mkItem(itemEqual, "="),
+			mkItem(itemTimeValue, "1979-05-27"),
+			tEOF,
+		}},
+		{"table", "[table]", []item{
+			mkItem(itemLeftBracket, "["),
+			mkItem(itemKey, "table"),
+			mkItem(itemRightBracket, "]"),
+			tEOF,
+		}},
+		{"table with key/value pairs", "[table-1]\nkey1 = \"some string\"\nkey2 = true\n", []item{
+			mkItem(itemLeftBracket, "["),
+			mkItem(itemKey, "table-1"),
+			mkItem(itemRightBracket, "]"),
+			mkItem(itemKey, "key1"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "some string"),
+			mkItem(itemKey, "key2"),
+			mkItem(itemEqual, "="),
+			mkItem(itemBooleanValue, "true"),
+			tEOF,
+		}},
+		{"table with dotted key", "[dog.\"tater.man\"]", []item{
+			mkItem(itemLeftBracket, "["),
+			mkItem(itemKey, "dog"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "tater.man"),
+			mkItem(itemRightBracket, "]"),
+			tEOF,
+		}},
+		{"table with whitespace", "[ j . \"k\" . 'l' ]  # comment", []item{
+			mkItem(itemLeftBracket, "["),
+			mkItem(itemKey, "j"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "k"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "l"),
+			mkItem(itemRightBracket, "]"),
+			tEOF,
+		}},
+		{"array of tables", "[[products]]\nname = \"Hammer\"\n\n[[products]]\n", []item{
+			mkItem(itemDoubleLeftBracket, "[["),
+			mkItem(itemKey, "products"),
+			mkItem(itemDoubleRightBracket, "]]"),
+			mkItem(itemKey, "name"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "Hammer"),
+			mkItem(itemDoubleLeftBracket, "[["),
+			mkItem(itemKey, "products"),
+			mkItem(itemDoubleRightBracket, "]]"),
+			tEOF,
+		}},
+		{"array of tables with dotted key", "[[ fruits . varieties ]]", []item{
+			mkItem(itemDoubleLeftBracket, "[["),
+			mkItem(itemKey, "fruits"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "varieties"),
+			mkItem(itemDoubleRightBracket, "]]"),
+			tEOF,
+		}},
+		{"dotted key", "physical.color = \"orange\"", []item{
+			mkItem(itemKey, "physical"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "color"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "orange"),
+			tEOF,
+		}},
+		{"dotted key with whitespace", "fruit . flavor='banana'", []item{
+			mkItem(itemKey, "fruit"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "flavor"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "banana"),
+			tEOF,
+		}},
+		{"dotted quoted key", `site."google.com"=true`, []item{
+			mkItem(itemKey, "site"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "google.com"),
+			mkItem(itemEqual, "="),
+			mkItem(itemBooleanValue, "true"),
+			tEOF,
+		}},
+		{"dotted key digits", "3.14159 = \"pi\"", []item{
+			mkItem(itemKey, "3"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "14159"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "pi"),
+			tEOF,
+		}},
+		{"quoted key followed by comment", `key = "value"#comment`, []item{
+			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "value"),
+			tEOF,
+		}},
+		{"invalid dotted key", "a. = 1", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemDot, "."),
+			mkItem(itemError, "expected key after `.`: `U+003D '='`"),
+		}},
+		{"array", "ports = [ 8000, 8001, 8002 ]", []item{
+			mkItem(itemKey, "ports"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "8000"),
+			mkItem(itemComma, ","),
+			mkItem(itemIntegerValue, "8001"),
+			mkItem(itemComma, ","),
+			mkItem(itemIntegerValue, "8002"),
+			mkItem(itemArrayEnd, "]"),
+			tEOF,
+		}},
+		{"empty array", "a = []", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemArrayEnd, "]"),
+			tEOF,
+		}},
+		{"nested array", "a = [ [ 1, 2 ], ['a', \"b\"], [1.0, true] ]", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemIntegerValue, "2"),
+			mkItem(itemArrayEnd, "]"),
+			mkItem(itemComma, ","),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemStringValue, "a"),
+			mkItem(itemComma, ","),
+			mkItem(itemStringValue, "b"),
+			mkItem(itemArrayEnd, "]"),
+			mkItem(itemComma, ","),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemFloatValue, "1.0"),
+			mkItem(itemComma, ","),
+			mkItem(itemBooleanValue, "true"),
+			mkItem(itemArrayEnd, "]"),
+			mkItem(itemArrayEnd, "]"),
+			tEOF,
+		}},
+		{"multi-line array with comments and trailing comma", "a = [\n  1, # one\r\n  2, # two\n  # nothing\n]\nb = 1", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemIntegerValue, "2"),
+			mkItem(itemComma, ","),
+			mkItem(itemArrayEnd, "]"),
+			mkItem(itemKey, "b"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			tEOF,
+		}},
+		{"inline table", "point = { x = 1, y = 2 }", []item{
+			mkItem(itemKey, "point"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "x"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemKey, "y"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "2"),
+			mkItem(itemInlineTableEnd, "}"),
+			tEOF,
+		}},
+		{"empty inline table", "a = {}", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemInlineTableEnd, "}"),
+			tEOF,
+		}},
+		{"inline table with dotted key and nested values", "animal = {type.name=\"pug\",tags=[{a={}}]}", []item{
+			mkItem(itemKey, "animal"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "type"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "name"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "pug"),
+			mkItem(itemComma, ","),
+			mkItem(itemKey, "tags"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemInlineTableEnd, "}"),
+			mkItem(itemInlineTableEnd, "}"),
+			mkItem(itemArrayEnd, "]"),
+			mkItem(itemInlineTableEnd, "}"),
+			tEOF,
+		}},
+		{"invalid array unterminated", "a = [1, 2", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemIntegerValue, "2"),
+			mkItem(itemError, "unterminated array"),
+		}},
+		{"invalid array only comma", "a = [,]", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemError, "unexpected `,` in array"),
+		}},
+		{"invalid array double comma", "a = [1,,2]", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemError, "unexpected `,` in array"),
+		}},
+		{"invalid array missing comma", "a = [1 2]", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemArrayStart, "["),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemError, "expected `,` or `]` after array element: `U+0032 '2'`"),
+		}},
+		{"invalid inline table newline", "a = {\nb = 1}", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemError, "newline is not allowed in inline table"),
+		}},
+		{"invalid inline table trailing comma", "a = {b = 1,}", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "b"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemComma, ","),
+			mkItem(itemError, "trailing comma is not allowed in inline table"),
+		}},
+		{"invalid inline table missing comma", "a = {b = 1 c = 2}", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "b"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemError, "expected `,` or `}` after key/value pair in inline table: `U+0063 'c'`"),
+		}},
+		{"invalid inline table missing equal", "a = {b 1}", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "b"),
+			mkItem(itemError, "expected `=` after key in inline table: `U+0031 '1'`"),
+		}},
+		{"invalid inline table unterminated", "a = {b = 1", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemInlineTableStart, "{"),
+			mkItem(itemKey, "b"),
+			mkItem(itemEqual, "="),
+			mkItem(itemIntegerValue, "1"),
+			mkItem(itemError, "unterminated inline table"),
+		}},
+		{"key = literal string UTF-8", "key = '日本語 ʎǝʞ'", []item{
+			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "日本語 ʎǝʞ"),
+			tEOF,
+		}},
+		{"key = basic string UTF-8", "key = \"José 🎉\"", []item{
+			mkItem(itemKey, "key"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "José 🎉"),
+			tEOF,
+		}},
+		{"key = multi-line strings UTF-8", "a = \"\"\"\nτόμλ\"\"\"\nb = '''\nτόμλ'''", []item{
+			mkItem(itemKey, "a"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "τόμλ"),
+			mkItem(itemKey, "b"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "τόμλ"),
+			tEOF,
+		}},
+		{"quoted keys UTF-8", "[ j . \"ʞ\" . 'l' ]\n'ʎǝʞ' = \"値\" # コメント", []item{
+			mkItem(itemLeftBracket, "["),
+			mkItem(itemKey, "j"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "ʞ"),
+			mkItem(itemDot, "."),
+			mkItem(itemKey, "l"),
+			mkItem(itemRightBracket, "]"),
+			mkItem(itemKey, "ʎǝʞ"),
+			mkItem(itemEqual, "="),
+			mkItem(itemStringValue, "値"),
+			tEOF,
+		}},
+		{"invalid bare key UTF-8", "ʎǝʞ = 1", []item{
+			mkItem(itemError, "invalid character: `U+028E 'ʎ'`"),
+		}},
+		{"invalid UTF-8 in string", "a = \"\xff\"", []item{
+			mkItem(itemError, "invalid UTF-8 byte: `0xff`"),
+		}},
+		{"invalid UTF-8 in comment", "# \xe6\x97", []item{
+			mkItem(itemError, "invalid UTF-8 byte: `0xe6`"),
+		}},
+		{"invalid date-time month", "d = 1979-13-27", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "month out of range: 13"),
+		}},
+		{"invalid date-time month 00", "d = 1979-00-27", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "month out of range: 00"),
+		}},
+		{"invalid date-time day", "d = 1979-05-32", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "day out of range: 1979-05-32"),
+		}},
+		{"invalid date-time day not leap year", "d = 1900-02-29", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "day out of range: 1900-02-29"),
+		}},
+		{"invalid date-time day short month", "d = 1979-04-31", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "day out of range: 1979-04-31"),
+		}},
+		{"invalid date-time hour", "d = 1979-05-27T24:00:00", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "hour out of range: 24"),
+		}},
+		{"invalid date-time minute", "d = 07:60:00", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "minute out of range: 60"),
+		}},
+		{"invalid date-time second", "d = 07:32:60", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "second out of range: 60"),
+		}},
+		{"invalid date-time missing second", "d = 07:32", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "unexpected EOF in time"),
+		}},
+		{"invalid date-time single digit month", "d = 1979-5-27", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "expected 2 digit month: `U+002D '-'`"),
+		}},
+		{"invalid date-time missing fraction", "d = 07:32:00.", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "unexpected EOF in time"),
+		}},
+		{"invalid date-time fraction", "d = 07:32:00.x", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "expected digit after decimal point in time: `U+0078 'x'`"),
+		}},
+		{"invalid date-time offset on local time", "d = 07:32:00Z", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "unexpected offset on local time: `U+005A 'Z'`"),
+		}},
+		{"invalid date-time offset hour", "d = 1979-05-27T07:32:00+24:00", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "offset hour out of range: 24"),
+		}},
+		{"invalid date-time offset without minute", "d = 1979-05-27T07:32:00+07", []item{
+			mkItem(itemKey, "d"),
+			mkItem(itemEqual, "="),
+			mkItem(itemError, "unexpected EOF in offset"),
+		}},
 	} {
 		items := collect(&test)
 		if !equal(items, test.items, false) {
@@ -365,6 +861,273 @@ func TestLex(t *testing.T) {
 	}
 }
 
+var lexPosTests = []lexTest{
+	{"empty", "", []item{{typ: itemEOF, pos: 0, end: 0, val: "", line: 1}}},
+	{"strings", "a = \"x\"\n'b' = '''\ny'''\nc = \"\"\"z\"\"\"", []item{
+		{typ: itemKey, pos: 0, end: 1, val: "a", line: 1},
+		{typ: itemEqual, pos: 2, end: 3, val: "=", line: 1},
+		{typ: itemStringValue, pos: 4, end: 7, val: "x", line: 1, style: styleBasic},
+		{typ: itemKey, pos: 8, end: 11, val: "b", line: 2, style: styleLiteral},
+		{typ: itemEqual, pos: 12, end: 13, val: "=", line: 2},
+		{typ: itemStringValue, pos: 14, end: 22, val: "y", line: 2, style: styleMultiLineLiteral},
+		{typ: itemKey, pos: 23, end: 24, val: "c", line: 4},
+		{typ: itemEqual, pos: 25, end: 26, val: "=", line: 4},
+		{typ: itemStringValue, pos: 27, end: 34, val: "z", line: 4, style: styleMultiLineBasic},
+		{typ: itemEOF, pos: 34, end: 34, val: "", line: 4},
+	}},
+	{"UTF-8", "'ʎǝʞ' = \"日本語\"\nk = 'é'", []item{
+		{typ: itemKey, pos: 0, end: 8, val: "ʎǝʞ", line: 1, style: styleLiteral},
+		{typ: itemEqual, pos: 9, end: 10, val: "=", line: 1},
+		{typ: itemStringValue, pos: 11, end: 22, val: "日本語", line: 1, style: styleBasic},
+		{typ: itemKey, pos: 23, end: 24, val: "k", line: 2},
+		{typ: itemEqual, pos: 25, end: 26, val: "=", line: 2},
+		{typ: itemStringValue, pos: 27, end: 31, val: "é", line: 2, style: styleLiteral},
+		{typ: itemEOF, pos: 31, end: 31, val: "", line: 2},
+	}},
+	{"integers", "a = [1_000, 0xdead_BEEF, 0o7, 0b1, 1.5]", []item{
+		{typ: itemKey, pos: 0, end: 1, val: "a", line: 1},
+		{typ: itemEqual, pos: 2, end: 3, val: "=", line: 1},
+		{typ: itemArrayStart, pos: 4, end: 5, val: "[", line: 1},
+		{typ: itemIntegerValue, pos: 5, end: 10, val: "1_000", line: 1, style: styleDecimal},
+		{typ: itemComma, pos: 10, end: 11, val: ",", line: 1},
+		{typ: itemIntegerValue, pos: 12, end: 23, val: "0xdead_BEEF", line: 1, style: styleHex},
+		{typ: itemComma, pos: 23, end: 24, val: ",", line: 1},
+		{typ: itemIntegerValue, pos: 25, end: 28, val: "0o7", line: 1, style: styleOctal},
+		{typ: itemComma, pos: 28, end: 29, val: ",", line: 1},
+		{typ: itemIntegerValue, pos: 30, end: 33, val: "0b1", line: 1, style: styleBinary},
+		{typ: itemComma, pos: 33, end: 34, val: ",", line: 1},
+		{typ: itemFloatValue, pos: 35, end: 38, val: "1.5", line: 1},
+		{typ: itemArrayEnd, pos: 38, end: 39, val: "]", line: 1},
+		{typ: itemEOF, pos: 39, end: 39, val: "", line: 1},
+	}},
+	{"invalid UTF-8", "a = 1\nb = 'ok'\nc = 'n\xc3g'", []item{
+		{typ: itemError, pos: 21, end: 21, val: "invalid UTF-8 byte: `0xc3`", line: 3},
+	}},
+}
+
+// A lexErrorTest is an input the lexer rejects, with the error and the
+// position and line it is reported at.
+type lexErrorTest struct {
+	name  string
+	input string
+	err   string
+	pos   Pos
+	line  int
+}
+
+// lexStringErrorTests are the strings the spec does not allow,
+// with the position the error is reported at.
+var lexStringErrorTests = []lexErrorTest{
+	{"basic unterminated", `s = "abc`, "unterminated basic string", 8, 1},
+	{"basic newline", "s = \"abc\nd\"", "unterminated basic string", 8, 1},
+	{"basic CRLF", "s = \"abc\r\nd\"", "unterminated basic string", 8, 1},
+	{"basic null", "s = \"a\x00b\"", "unexpected control character in basic string: `U+0000`", 6, 1},
+	{"basic CR", "s = \"a\rb\"", "unexpected control character in basic string: `U+000D`", 6, 1},
+	{"basic DEL", "s = \"a\x7f\"", "unexpected control character in basic string: `U+007F`", 6, 1},
+	{"basic escape", `s = "\a"`, "invalid escape: `U+0061 'a'`", 6, 1},
+	{"basic escape unicode short", `s = "\u00g0"`, "expected hex digit in unicode escape: `U+0067 'g'`", 9, 1},
+	{"basic escape unicode surrogate", `s = "\uD800"`, "invalid unicode scalar: `\\uD800`", 5, 1},
+	{"basic escape unicode range", `s = "\U00110000"`, "invalid unicode scalar: `\\U00110000`", 5, 1},
+	{"basic escape EOF", `x = "\`, "unterminated basic string", 6, 1},
+	{"basic escape unicode EOF", `x = "\u`, "unterminated basic string", 7, 1},
+	{"basic escape unicode short EOF", `x = "\U0001`, "unterminated basic string", 11, 1},
+	{"multi-line basic escape EOF", `x = """\`, "unterminated multi-line basic string", 8, 1},
+	{"multi-line basic line ending backslash EOF", `x = """\ `, "unexpected character after line ending backslash: EOF", 9, 1},
+	{"basic key unterminated", `"abc = 1`, "unterminated basic string", 8, 1},
+	{"literal unterminated", `s = 'abc`, "unterminated literal string", 8, 1},
+	{"literal newline", "s = 'a\nb'", "unterminated literal string", 6, 1},
+	{"literal null", "s = 'a\x00'", "unexpected control character in literal string: `U+0000`", 6, 1},
+	{"literal DEL", "s = 'a\x7f'", "unexpected control character in literal string: `U+007F`", 6, 1},
+	{"literal key newline", "'a\nb' = 1", "unterminated literal string", 2, 1},
+	{"multi-line basic unterminated", `s = """abc`, "unterminated multi-line basic string", 10, 1},
+	{"multi-line basic unterminated quotes", `s = """abc""`, "unterminated multi-line basic string", 12, 1},
+	{"multi-line basic unterminated lines", "s = \"\"\"\na\nb", "unterminated multi-line basic string", 11, 3},
+	{"multi-line basic null", "s = \"\"\"a\x00\"\"\"", "unexpected control character in multi-line basic string: `U+0000`", 8, 1},
+	{"multi-line basic CR", "s = \"\"\"a\rb\"\"\"", "unexpected control character in multi-line basic string: `U+000D`", 8, 1},
+	{"multi-line basic escape", "s = \"\"\"\na\\qb\"\"\"", "invalid escape: `U+0071 'q'`", 10, 2},
+	{"multi-line basic line ending backslash", "s = \"\"\"a\\ b\"\"\"", "unexpected character after line ending backslash: `U+0062 'b'`", 10, 1},
+	{"multi-line basic too many quotes", `s = """a""""""`, "too many quotation marks before the closing delimiter", 13, 1},
+	{"multi-line literal too many quotes", `s = '''a''''''`, "too many quotation marks before the closing delimiter", 13, 1},
+	{"multi-line literal unterminated", `s = '''abc`, "unterminated multi-line literal string", 10, 1},
+	{"multi-line literal null", "s = '''a\x00'''", "unexpected control character in multi-line literal string: `U+0000`", 8, 1},
+	{"array bare CR", "a = [1,\r2]", "invalid character: `U+000D`", 7, 1},
+	{"multi-line literal CR", "s = '''\na\rb'''", "unexpected control character in multi-line literal string: `U+000D`", 9, 2},
+}
+
+// lexFloatErrorTests are the floats the spec does not allow, with the
+// position the error is reported at.
+var lexFloatErrorTests = []lexErrorTest{
+	{"no fraction", "f = 1.", "expected digit after decimal point: EOF", 6, 1},
+	{"no fraction before exponent", "f = 1.e5", "expected digit after decimal point: `U+0065 'e'`", 6, 1},
+	{"no fraction in array", "f = [1.]", "expected digit after decimal point: `U+005D ']'`", 7, 1},
+	{"no integer part", "f = .5", "expected digit before decimal point", 4, 1},
+	{"signed no integer part", "f = -.5", "expected digit before decimal point", 5, 1},
+	{"no exponent", "f = 1e", "expected digit in exponent: EOF", 6, 1},
+	{"no exponent after sign", "f = 1e+", "expected digit in exponent: EOF", 7, 1},
+	{"no exponent after fraction", "f = 1.5E-", "expected digit in exponent: EOF", 9, 1},
+	{"exponent underscore", "f = 1e_5", "expected digit in exponent: `U+005F '_'`", 6, 1},
+	{"exponent double sign", "f = 1e+-5", "expected digit in exponent: `U+002D '-'`", 7, 1},
+	{"two decimal points", "f = 1.2.3", "unexpected second decimal point in float", 7, 1},
+	{"decimal point in exponent", "f = 1e2.5", "unexpected decimal point in exponent", 7, 1},
+	{"two exponents", "f = 1e2e3", "unexpected second exponent in float", 7, 1},
+	{"sign without exponent", "f = 1.5+3", "unexpected sign `U+002B '+'`, an exponent must start with `e`", 7, 1},
+	{"sign after integer", "f = 1-3", "unexpected sign `U+002D '-'`, an exponent must start with `e`", 5, 1},
+	{"underscore before decimal point", "f = 1_.5", "expected integer after '_'", 6, 1},
+	{"underscore after decimal point", "f = 1._5", "expected digit after decimal point: `U+005F '_'`", 6, 1},
+	{"underscore before exponent", "f = 1_e5", "expected integer after '_'", 6, 1},
+	{"underscore at end", "f = 1.5_", "expected integer after '_'", 8, 1},
+	{"double underscore", "f = 1.5__0", "expected integer after '_'", 8, 1},
+	{"leading zero", "f = 01.5", "leading zeros are not allowed in the integer part of a float", 4, 1},
+	{"signed leading zero", "f = -00e1", "leading zeros are not allowed in the integer part of a float", 5, 1},
+}
+
+// lexIntegerErrorTests are the integers the spec does not allow, with the
+// position the error is reported at.
+var lexIntegerErrorTests = []lexErrorTest{
+	{"leading zero", "i = 01", "leading zeros are not allowed in a decimal integer", 4, 1},
+	{"leading zeros", "i = 0001", "leading zeros are not allowed in a decimal integer", 4, 1},
+	{"signed leading zero", "i = -01", "leading zeros are not allowed in a decimal integer", 5, 1},
+	{"leading zero underscore", "i = 0_1", "leading zeros are not allowed in a decimal integer", 4, 1},
+	{"trailing underscore", "i = 1_", "expected integer after '_'", 6, 1},
+	{"double underscore", "i = 1__2", "expected integer after '_'", 6, 1},
+	{"sign only", "i = +", "expected digit character: EOF", 5, 1},
+	{"double sign", "i = +-1", "expected digit character: `U+002D '-'`", 5, 1},
+	{"signed hex", "i = +0x10", "a sign is not allowed on hexadecimal integers", 4, 1},
+	{"signed octal", "i = -0o7", "a sign is not allowed on octal integers", 4, 1},
+	{"signed binary", "i = -0b1", "a sign is not allowed on binary integers", 4, 1},
+	{"bare hex prefix", "i = 0x", "expected hexadecimal digit after `0x`: EOF", 6, 1},
+	{"bare octal prefix", "i = [0o]", "expected octal digit after `0o`: `U+005D ']'`", 7, 1},
+	{"bare binary prefix", "i = 0b # c", "expected binary digit after `0b`: `U+0020 ' '`", 6, 1},
+	{"hex leading underscore", "i = 0x_1", "expected hexadecimal digit after `0x`: `U+005F '_'`", 6, 1},
+	{"hex trailing underscore", "i = 0xa_", "expected integer after '_'", 8, 1},
+	{"octal invalid digit", "i = 0o78", "invalid octal digit: `U+0038 '8'`", 7, 1},
+	{"octal first digit", "i = 0o8", "expected octal digit after `0o`: `U+0038 '8'`", 6, 1},
+	{"binary invalid digit", "i = 0b102", "invalid binary digit: `U+0032 '2'`", 8, 1},
+	{"binary hex digit", "i = 0b1f", "invalid binary digit: `U+0066 'f'`", 7, 1},
+	{"hex fraction", "i = 0x1.5", "invalid hexadecimal digit: `U+002E '.'`", 7, 1},
+	{"uppercase hex prefix", "i = 0X10", "a base prefix must be lowercase: `0X`", 5, 1},
+	{"uppercase octal prefix", "i = 0O7", "a base prefix must be lowercase: `0O`", 5, 1},
+}
+
+func TestLexIntegerError(t *testing.T) {
+	testLexError(t, lexIntegerErrorTests)
+}
+
+func TestLexFloatError(t *testing.T) {
+	testLexError(t, lexFloatErrorTests)
+}
+
+func TestLexStringError(t *testing.T) {
+	testLexError(t, lexStringErrorTests)
+}
+
+// testLexError checks that the lexer stops at the error of each test.
+func testLexError(t *testing.T, tests []lexErrorTest) {
+	t.Helper()
+	for _, test := range tests {
+		l := lex(test.name, test.input)
+		var last item
+		for last = l.nextItem(); last.typ != itemEOF && last.typ != itemError; {
+			last = l.nextItem()
+		}
+		want := item{typ: itemError, pos: test.pos, end: test.pos, val: test.err, line: test.line}
+		if last != want {
+			t.Errorf("%s: got %+v at %d line %d, expected %+v at %d line %d",
+				test.name, last, last.pos, last.line, want, want.pos, want.line)
+		}
+	}
+}
+
+var lexTriviaTests = []lexTest{
+	{"comment", "# hello\n", []item{
+		mkItem(itemComment, "# hello"),
+		mkItem(itemNewline, "\n"),
+		tEOF,
+	}},
+	{"key/value", "\ta . b = 1 # one\r\n", []item{
+		mkItem(itemWhitespace, "\t"),
+		mkItem(itemKey, "a"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemDot, "."),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemKey, "b"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemEqual, "="),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemIntegerValue, "1"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemComment, "# one"),
+		mkItem(itemNewline, "\r\n"),
+		tEOF,
+	}},
+	{"table", "[ a ]\n\n", []item{
+		mkItem(itemLeftBracket, "["),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemKey, "a"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemRightBracket, "]"),
+		mkItem(itemNewline, "\n"),
+		mkItem(itemNewline, "\n"),
+		tEOF,
+	}},
+	{"array", "a=[ 1,#c\n  2 ]", []item{
+		mkItem(itemKey, "a"),
+		mkItem(itemEqual, "="),
+		mkItem(itemArrayStart, "["),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemIntegerValue, "1"),
+		mkItem(itemComma, ","),
+		mkItem(itemComment, "#c"),
+		mkItem(itemNewline, "\n"),
+		mkItem(itemWhitespace, "  "),
+		mkItem(itemIntegerValue, "2"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemArrayEnd, "]"),
+		tEOF,
+	}},
+	{"inline table", "a={ b=1 }", []item{
+		mkItem(itemKey, "a"),
+		mkItem(itemEqual, "="),
+		mkItem(itemInlineTableStart, "{"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemKey, "b"),
+		mkItem(itemEqual, "="),
+		mkItem(itemIntegerValue, "1"),
+		mkItem(itemWhitespace, " "),
+		mkItem(itemInlineTableEnd, "}"),
+		tEOF,
+	}},
+}
+
+func TestLexTrivia(t *testing.T) {
+	for _, test := range lexTriviaTests {
+		l := lex(test.name, test.input)
+		l.options.emitTrivia = true
+		var items []item
+		for {
+			item := l.nextItem()
+			items = append(items, item)
+			if item.typ == itemEOF || item.typ == itemError {
+				break
+			}
+		}
+		if !equal(items, test.items, false) {
+			t.Errorf("%s: got\n\t%+v\nexpected\n\t%+v", test.name, items, test.items)
+		}
+	}
+}
+
+// The other tests don't check position, to make the test cases easier to construct.
+// This one does.
+func TestPos(t *testing.T) {
+	for _, test := range lexPosTests {
+		items := collect(&test)
+		if !equal(items, test.items, true) {
+			t.Errorf("%s: got\n\t%+v\nexpected\n\t%+v", test.name, items, test.items)
+		}
+	}
+}
+
 // collect gathers the emitted items into a slice.
 func collect(t *lexTest) (items []item) {
 	l := lex(t.name, t.input)
@@ -395,6 +1158,9 @@ func equal(i1, i2 []item, checkPos bool) bool {
 		if checkPos && i1[k].line != i2[k].line {
 			return false
 		}
+		if checkPos && (i1[k].end != i2[k].end || i1[k].style != i2[k].style) {
+			return false
+		}
 	}
 	return true
 }
@@ -443,3 +1209,67 @@ func Test_lexer_isNextString(t *testing.T) {
 		})
 	}
 }
+
+func TestLexStopEarly(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		l := lex("stop", "a = 1\nb = 2\n")
+		if item := l.nextItem(); item.typ != itemKey {
+			t.Fatalf("got %v, expected key", item)
+		}
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("lexers left %d goroutines running", after-before)
+	}
+}
+
+func TestLexAfterEnd(t *testing.T) {
+	for _, input := range []string{"a = 1", "a = "} {
+		l := lex("end", input)
+		for item := l.nextItem(); item.typ != itemEOF && item.typ != itemError; {
+			item = l.nextItem()
+		}
+		for i := 0; i < 3; i++ {
+			if item := l.nextItem(); item.typ != itemEOF {
+				t.Errorf("%q: got %v after the end, expected EOF", input, item)
+			}
+		}
+	}
+}
+
+const benchInput = `# This is a TOML document.
+title = "TOML Example"
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00-08:00
+
+[database]
+enabled = true
+ports = [ 8000, 8001, 8002 ]
+data = [ ["delta", "phi"], [3.14] ]
+temp_targets = { cpu = 79.5, case = 72.0 }
+
+[servers]
+
+[servers.alpha]
+ip = "10.0.0.1"
+role = "frontend"
+
+[servers.beta]
+ip = "10.0.0.2"
+role = "backend"
+`
+
+func BenchmarkLex(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := lex("bench", benchInput)
+		for {
+			item := l.nextItem()
+			if item.typ == itemEOF || item.typ == itemError {
+				break
+			}
+		}
+	}
+}
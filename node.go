@@ -1,5 +1,22 @@
 package toml
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A Node is an element in the parse tree.
+type Node interface {
+	Type() NodeType
+	String() string
+	Position() Pos // byte position of start of node in full original input string
+	Line() int     // line number of start of node in full original input string
+}
+
+// NodeType identifies the type of a parse tree node.
+type NodeType int
+
 // Pos represents a byte position in the original input text from which
 // this template was parsed.
 type Pos int
@@ -7,3 +24,315 @@ type Pos int
 func (p Pos) Position() Pos {
 	return p
 }
+
+// line represents the line number in the original input text from which
+// a node was parsed.
+type line int
+
+func (l line) Line() int {
+	return int(l)
+}
+
+// Type returns itself and provides an easy default implementation
+// for embedding in a Node. Embedded in all non-trivial Nodes.
+func (t NodeType) Type() NodeType {
+	return t
+}
+
+const (
+	NodeKey         NodeType = iota // A key, possibly dotted.
+	NodeKeyValue                    // A key/value pair.
+	NodeTable                       // A [table] and its key/value pairs.
+	NodeArrayTable                  // An [[array of tables]] element and its key/value pairs.
+	NodeArray                       // An array value.
+	NodeInlineTable                 // An inline table value.
+	NodeString                      // A string value.
+	NodeInteger                     // An integer value.
+	NodeFloat                       // A float value.
+	NodeBool                        // A boolean value.
+	NodeDateTime                    // A date-time, date or time value.
+)
+
+// Document holds the parse tree of a TOML document.
+type Document struct {
+	Name  string // name of the document, used only for error reports
+	Nodes []Node // top-level *KeyValueNode, *TableNode and *ArrayTableNode in source order
+}
+
+func (d *Document) String() string {
+	var sb strings.Builder
+	for i, n := range d.Nodes {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(n.String())
+	}
+	return sb.String()
+}
+
+// KeyNode holds a key. Each part of a dotted key is an element of Parts.
+type KeyNode struct {
+	NodeType
+	Pos
+	line
+	Parts []string // unquoted parts of the key
+}
+
+func (k *KeyNode) String() string {
+	return formatKey(k.Parts)
+}
+
+// KeyValueNode holds a key/value pair.
+type KeyValueNode struct {
+	NodeType
+	Pos
+	line
+	Key   *KeyNode
+	Value Node
+}
+
+func (kv *KeyValueNode) String() string {
+	return kv.Key.String() + " = " + kv.Value.String()
+}
+
+// TableNode holds a [table] header and the key/value pairs under it.
+type TableNode struct {
+	NodeType
+	Pos
+	line
+	Key       *KeyNode
+	KeyValues []*KeyValueNode
+}
+
+func (t *TableNode) String() string {
+	return formatSection("["+t.Key.String()+"]", t.KeyValues)
+}
+
+// ArrayTableNode holds an [[array of tables]] header and the key/value pairs
+// of the table element it appends.
+type ArrayTableNode struct {
+	NodeType
+	Pos
+	line
+	Key       *KeyNode
+	KeyValues []*KeyValueNode
+}
+
+func (t *ArrayTableNode) String() string {
+	return formatSection("[["+t.Key.String()+"]]", t.KeyValues)
+}
+
+func formatSection(header string, kvs []*KeyValueNode) string {
+	var sb strings.Builder
+	sb.WriteString(header)
+	for _, kv := range kvs {
+		sb.WriteByte('\n')
+		sb.WriteString(kv.String())
+	}
+	return sb.String()
+}
+
+// ArrayNode holds an array value.
+type ArrayNode struct {
+	NodeType
+	Pos
+	line
+	Values []Node
+}
+
+func (a *ArrayNode) String() string {
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for i, v := range a.Values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(v.String())
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+// InlineTableNode holds an inline table value.
+type InlineTableNode struct {
+	NodeType
+	Pos
+	line
+	KeyValues []*KeyValueNode
+}
+
+func (t *InlineTableNode) String() string {
+	if len(t.KeyValues) == 0 {
+		return "{}"
+	}
+	var sb strings.Builder
+	sb.WriteString("{ ")
+	for i, kv := range t.KeyValues {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(kv.String())
+	}
+	sb.WriteString(" }")
+	return sb.String()
+}
+
+// StringNode holds a string value.
+type StringNode struct {
+	NodeType
+	Pos
+	line
+	Value string // the string, after escape processing
+}
+
+func (s *StringNode) String() string {
+	return quoteString(s.Value)
+}
+
+// IntegerNode holds an integer value.
+type IntegerNode struct {
+	NodeType
+	Pos
+	line
+	Text  string // the original textual representation from the input
+	Value int64
+}
+
+func (i *IntegerNode) String() string {
+	return i.Text
+}
+
+// FloatNode holds a float value.
+type FloatNode struct {
+	NodeType
+	Pos
+	line
+	Text  string // the original textual representation from the input
+	Value float64
+}
+
+func (f *FloatNode) String() string {
+	return f.Text
+}
+
+// BoolNode holds a boolean value.
+type BoolNode struct {
+	NodeType
+	Pos
+	line
+	Value bool
+}
+
+func (b *BoolNode) String() string {
+	if b.Value {
+		return "true"
+	}
+	return "false"
+}
+
+// DateTimeKind identifies which of the date-time forms a DateTimeNode holds.
+type DateTimeKind int
+
+const (
+	KindOffsetDateTime DateTimeKind = iota // 1979-05-27T07:32:00Z
+	KindLocalDateTime                      // 1979-05-27T07:32:00
+	KindLocalDate                          // 1979-05-27
+	KindLocalTime                          // 07:32:00
+)
+
+func (k DateTimeKind) String() string {
+	switch k {
+	case KindOffsetDateTime:
+		return "offset date-time"
+	case KindLocalDateTime:
+		return "local date-time"
+	case KindLocalDate:
+		return "local date"
+	case KindLocalTime:
+		return "local time"
+	}
+	return fmt.Sprintf("DateTimeKind(%d)", int(k))
+}
+
+// DateTimeNode holds an offset date-time, local date-time, local date or
+// local time value. The local forms are not tied to any time zone, their
+// Value holds the date and time of day as written, in UTC, so that no time
+// zone rule can shift them. A local time is set on January 1, year 0.
+// LocalDateTimeOf, LocalDateOf and LocalTimeOf return the local forms
+// without a time zone.
+type DateTimeNode struct {
+	NodeType
+	Pos
+	line
+	Text  string // the original textual representation from the input
+	Kind  DateTimeKind
+	Value time.Time
+}
+
+func (d *DateTimeNode) String() string {
+	return d.Text
+}
+
+// formatKey formats the parts of a key, quoting any of them
+// that can not be written as a bare key.
+func formatKey(parts []string) string {
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = quoteKey(part)
+	}
+	return strings.Join(quoted, ".")
+}
+
+// quoteKey returns key as a bare key if possible, otherwise as a basic string.
+func quoteKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, c := range key {
+		if !isBareKey(c) {
+			return quoteString(key)
+		}
+	}
+	return key
+}
+
+// quoteString returns s as a basic string.
+// https://github.com/toml-lang/toml#string
+func quoteString(s string) string {
+	return `"` + escapeString(s, false) + `"`
+}
+
+// escapeString escapes s for a basic string. Newlines are kept as they are
+// if multiLine is set.
+func escapeString(s string, multiLine bool) string {
+	var sb strings.Builder
+	for _, c := range s {
+		switch c {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			if multiLine {
+				sb.WriteByte('\n')
+				continue
+			}
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if 0x00 <= c && c <= 0x1f || c == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, c)
+				continue
+			}
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,216 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// parseInteger returns the value of the TOML integer text.
+// https://github.com/toml-lang/toml#integer
+//
+// Decimal integers may have a sign but no leading zeros. Hexadecimal,
+// octal and binary integers have a prefix and no sign. An underscore must
+// be between two digits. The value must fit in a 64-bit signed integer.
+func parseInteger(text string) (int64, error) {
+	fail := func(msg string) (int64, error) {
+		return 0, fmt.Errorf("invalid integer %s: %s", text, msg)
+	}
+	s, sign := text, ""
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		sign, s = s[:1], s[1:]
+	}
+	base := 10
+	if len(s) > 1 && s[0] == '0' {
+		switch s[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+	}
+	if base != 10 {
+		if sign != "" {
+			return fail("a sign is not allowed with a base prefix")
+		}
+		s = s[2:]
+	}
+	if msg := checkDigits(s, base); msg != "" {
+		return fail(msg)
+	}
+	if base == 10 && len(s) > 1 && s[0] == '0' {
+		return fail("leading zeros are not allowed")
+	}
+	v, err := strconv.ParseInt(sign+strings.ReplaceAll(s, "_", ""), base, 64)
+	if err != nil {
+		return fail("value out of range")
+	}
+	return v, nil
+}
+
+// parseFloat returns the value of the TOML float text.
+// https://github.com/toml-lang/toml#float
+//
+// A float has an integer part, which follows the rules of decimal integers,
+// and a fractional part, an exponent or both. The special values inf and
+// nan may have a sign.
+func parseFloat(text string) (float64, error) {
+	fail := func(msg string) (float64, error) {
+		return 0, fmt.Errorf("invalid float %s: %s", text, msg)
+	}
+	s := text
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	switch s {
+	case "inf":
+		if text[0] == '-' {
+			return math.Inf(-1), nil
+		}
+		return math.Inf(1), nil
+	case "nan":
+		return math.NaN(), nil
+	}
+	mantissa, exp := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exp = s[:i], s[i+1:]
+		if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+			exp = exp[1:]
+		}
+		if msg := checkDigits(exp, 10); msg != "" {
+			return fail("exponent: " + msg)
+		}
+	}
+	intPart, frac := mantissa, ""
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		intPart, frac = mantissa[:i], mantissa[i+1:]
+		if msg := checkDigits(frac, 10); msg != "" {
+			return fail("fractional part: " + msg)
+		}
+	} else if mantissa == s {
+		return fail("expected a fractional part or an exponent")
+	}
+	if msg := checkDigits(intPart, 10); msg != "" {
+		return fail(msg)
+	}
+	if len(intPart) > 1 && intPart[0] == '0' {
+		return fail("leading zeros are not allowed")
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(text, "_", ""), 64)
+	if err != nil {
+		return fail("value out of range")
+	}
+	return v, nil
+}
+
+// checkDigits reports what is wrong with the digits s of the given base,
+// or returns the empty string if they are valid. Each underscore must be
+// between two digits.
+func checkDigits(s string, base int) string {
+	if s == "" {
+		return "expected digits"
+	}
+	for i, c := range s {
+		if c == '_' {
+			if i == 0 || i == len(s)-1 || s[i-1] == '_' {
+				return "underscores must be between digits"
+			}
+			continue
+		}
+		if d := digitValue(c); d < 0 || d >= base {
+			return fmt.Sprintf("invalid digit %q in base %d", c, base)
+		}
+	}
+	return ""
+}
+
+// digitValue returns the value of the hexadecimal digit c, or -1.
+func digitValue(c rune) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// Int64 returns the value of a TokenInteger.
+func (t Token) Int64() (int64, error) {
+	if t.Kind != TokenInteger {
+		return 0, t.kindError("an integer")
+	}
+	v, err := parseInteger(t.Raw)
+	if err != nil {
+		return 0, t.conversionError(err)
+	}
+	return v, nil
+}
+
+// Uint64 returns the value of a TokenInteger that is not negative.
+func (t Token) Uint64() (uint64, error) {
+	v, err := t.Int64()
+	if err != nil {
+		return 0, err
+	}
+	if v < 0 {
+		return 0, t.conversionError(fmt.Errorf("integer %s is negative", t.Raw))
+	}
+	return uint64(v), nil
+}
+
+// BigInt returns the value of a TokenInteger.
+func (t Token) BigInt() (*big.Int, error) {
+	v, err := t.Int64()
+	if err != nil {
+		return nil, err
+	}
+	return big.NewInt(v), nil
+}
+
+// Float64 returns the value of a TokenFloat or a TokenInteger.
+func (t Token) Float64() (float64, error) {
+	switch t.Kind {
+	case TokenInteger:
+		v, err := t.Int64()
+		return float64(v), err
+	case TokenFloat:
+		v, err := parseFloat(t.Raw)
+		if err != nil {
+			return 0, t.conversionError(err)
+		}
+		return v, nil
+	}
+	return 0, t.kindError("a float")
+}
+
+// BigFloat returns the value of a TokenFloat or a TokenInteger. It reports
+// an error for nan, which a big.Float can not represent.
+func (t Token) BigFloat() (*big.Float, error) {
+	v, err := t.Float64()
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(v) {
+		return nil, t.conversionError(fmt.Errorf("float %s can not be represented as a big.Float", t.Raw))
+	}
+	return big.NewFloat(v), nil
+}
+
+// conversionError returns the error err of a conversion of t with the
+// position of t.
+func (t Token) conversionError(err error) error {
+	return fmt.Errorf("toml: line %d, column %d: %v", t.Line, t.Column, err)
+}
+
+// kindError returns an error for a conversion of t, which is not of the
+// kind described by want.
+func (t Token) kindError(want string) error {
+	return t.conversionError(fmt.Errorf("%s %q is not %s", t.Kind, t.Raw, want))
+}
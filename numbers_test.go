@@ -0,0 +1,131 @@
+package toml
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseInteger(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want int64
+		err  string
+	}{
+		{"0", 0, ""},
+		{"+0", 0, ""},
+		{"-0", 0, ""},
+		{"1_000", 1000, ""},
+		{"-17", -17, ""},
+		{"0xDEAD_beef", 0xdeadbeef, ""},
+		{"0o755", 0o755, ""},
+		{"0b1101", 13, ""},
+		{"9223372036854775807", math.MaxInt64, ""},
+		{"-9223372036854775808", math.MinInt64, ""},
+		{"9223372036854775808", 0, "invalid integer 9223372036854775808: value out of range"},
+		{"0x8000000000000000", 0, "invalid integer 0x8000000000000000: value out of range"},
+		{"01234", 0, "invalid integer 01234: leading zeros are not allowed"},
+		{"-01", 0, "invalid integer -01: leading zeros are not allowed"},
+		{"0_1", 0, "invalid integer 0_1: leading zeros are not allowed"},
+		{"+0x10", 0, "invalid integer +0x10: a sign is not allowed with a base prefix"},
+		{"-0o7", 0, "invalid integer -0o7: a sign is not allowed with a base prefix"},
+		{"0x", 0, "invalid integer 0x: expected digits"},
+		{"1__0", 0, "invalid integer 1__0: underscores must be between digits"},
+		{"1_", 0, "invalid integer 1_: underscores must be between digits"},
+		{"0x_1", 0, "invalid integer 0x_1: underscores must be between digits"},
+		{"0b102", 0, "invalid integer 0b102: invalid digit '2' in base 2"},
+	} {
+		got, err := parseInteger(test.text)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: got error %v, expected %s", test.text, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%s: got %d, %v, expected %d", test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestParseFloat(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want float64
+		err  string
+	}{
+		{"1.5", 1.5, ""},
+		{"-0.0", 0, ""},
+		{"+1e3", 1000, ""},
+		{"6.626e-34", 6.626e-34, ""},
+		{"1E05", 1e5, ""},
+		{"9_224_617.445_991", 9224617.445991, ""},
+		{"inf", math.Inf(1), ""},
+		{"+inf", math.Inf(1), ""},
+		{"-inf", math.Inf(-1), ""},
+		{"00.5", 0, "invalid float 00.5: leading zeros are not allowed"},
+		{"-01.5", 0, "invalid float -01.5: leading zeros are not allowed"},
+		{"1.", 0, "invalid float 1.: fractional part: expected digits"},
+		{".5", 0, "invalid float .5: expected digits"},
+		{"1e", 0, "invalid float 1e: exponent: expected digits"},
+		{"1e+", 0, "invalid float 1e+: exponent: expected digits"},
+		{"1_.5", 0, "invalid float 1_.5: underscores must be between digits"},
+		{"1._5", 0, "invalid float 1._5: fractional part: underscores must be between digits"},
+		{"1e_5", 0, "invalid float 1e_5: exponent: underscores must be between digits"},
+		{"1", 0, "invalid float 1: expected a fractional part or an exponent"},
+		{"1e400", 0, "invalid float 1e400: value out of range"},
+	} {
+		got, err := parseFloat(test.text)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: got error %v, expected %s", test.text, err, test.err)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("%s: got %v, %v, expected %v", test.text, got, err, test.want)
+		}
+	}
+	if got, err := parseFloat("-nan"); err != nil || !math.IsNaN(got) {
+		t.Errorf("-nan: got %v, %v, expected NaN", got, err)
+	}
+}
+
+func TestTokenNumbers(t *testing.T) {
+	s := NewScanner("", "a = [0x7f, -3, 2.5, nan, 'x']", 0)
+	var values []Token
+	for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+		switch tok.Kind {
+		case TokenInteger, TokenFloat, TokenString:
+			values = append(values, tok)
+		}
+	}
+	if v, err := values[0].Uint64(); err != nil || v != 127 {
+		t.Errorf("Uint64: got %d, %v, expected 127", v, err)
+	}
+	if v, err := values[1].BigInt(); err != nil || v.Int64() != -3 {
+		t.Errorf("BigInt: got %v, %v, expected -3", v, err)
+	}
+	if v, err := values[1].Float64(); err != nil || v != -3 {
+		t.Errorf("Float64: got %v, %v, expected -3", v, err)
+	}
+	if v, err := values[2].BigFloat(); err != nil || v.String() != "2.5" {
+		t.Errorf("BigFloat: got %v, %v, expected 2.5", v, err)
+	}
+	for _, test := range []struct {
+		err  error
+		want string
+	}{
+		{second(values[1].Uint64()), "toml: line 1, column 12: integer -3 is negative"},
+		{second(values[2].Int64()), `toml: line 1, column 16: Float "2.5" is not an integer`},
+		{second(values[3].BigFloat()), "toml: line 1, column 21: float nan can not be represented as a big.Float"},
+		{second(values[4].Float64()), `toml: line 1, column 26: String "'x'" is not a float`},
+	} {
+		if test.err == nil || test.err.Error() != test.want {
+			t.Errorf("got error %v, expected %s", test.err, test.want)
+		}
+	}
+}
+
+func second(_ interface{}, err error) error {
+	return err
+}
@@ -0,0 +1,289 @@
+package toml
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// parser builds the parse tree from the items of the lexer.
+type parser struct {
+	name      string
+	lex       *lexer
+	token     [1]item // one-token lookahead for parser.
+	peekCount int
+}
+
+// Parse parses src as a TOML document and returns the parse tree.
+// name is used only in error reports. A syntax error is returned as a *SyntaxError.
+func Parse(name, src string) (doc *Document, err error) {
+	p := &parser{
+		name: name,
+		lex:  lex(name, src),
+	}
+	defer p.recover(&err)
+	doc = p.parse()
+	p.validate(doc)
+	return doc, nil
+}
+
+// next returns the next token.
+func (p *parser) next() item {
+	if p.peekCount > 0 {
+		p.peekCount--
+	} else {
+		p.token[0] = p.lex.nextItem()
+	}
+	if p.token[p.peekCount].typ == itemError {
+		p.errorf("%s", p.token[p.peekCount].val)
+	}
+	return p.token[p.peekCount]
+}
+
+// backup backs the input stream up one token.
+func (p *parser) backup() {
+	p.peekCount++
+}
+
+// peek returns but does not consume the next token.
+func (p *parser) peek() item {
+	if p.peekCount > 0 {
+		return p.token[p.peekCount-1]
+	}
+	p.peekCount = 1
+	p.token[0] = p.lex.nextItem()
+	return p.token[0]
+}
+
+// errorf formats the error as a *SyntaxError at the current token
+// and terminates processing.
+func (p *parser) errorf(format string, args ...interface{}) {
+	token := p.token[0]
+	panic(newSyntaxError(p.name, p.lex.input, token.pos, token.line, fmt.Sprintf(format, args...)))
+}
+
+// validate checks that doc defines no key or table twice and terminates
+// processing if it does. The error is reported at the second definition.
+func (p *parser) validate(doc *Document) {
+	_, err := buildTable(doc)
+	if err == nil {
+		return
+	}
+	e := err.(*defineError)
+	msg := fmt.Sprintf("%s at line %d, column %d", e.msg, e.prev.Line(), column(p.lex.input, int(e.prev.Position())))
+	panic(newSyntaxError(p.name, p.lex.input, e.n.Position(), e.n.Line(), msg))
+}
+
+// expect consumes the next token and guarantees it has the required type.
+func (p *parser) expect(expected itemType, context string) item {
+	token := p.next()
+	if token.typ != expected {
+		p.unexpected(token, context)
+	}
+	return token
+}
+
+// unexpected complains about the token and terminates processing.
+func (p *parser) unexpected(token item, context string) {
+	p.errorf("unexpected %s in %s", token, context)
+}
+
+// recover is the handler that turns panics into returns from the top level of Parse.
+func (p *parser) recover(errp *error) {
+	e := recover()
+	if e != nil {
+		if _, ok := e.(runtime.Error); ok {
+			panic(e)
+		}
+		*errp = e.(error)
+	}
+}
+
+// parse is the top-level parser for a document.
+// It runs to EOF.
+//
+//	toml = expression *( newline expression )
+//	expression =  ws [ comment ]
+//	expression =/ ws keyval ws [ comment ]
+//	expression =/ ws table ws [ comment ]
+func (p *parser) parse() *Document {
+	doc := &Document{Name: p.name}
+	// kvs is where key/value pairs are added, the key/value pairs
+	// of the last table header or nil for the root table.
+	var kvs *[]*KeyValueNode
+	for {
+		switch token := p.next(); token.typ {
+		case itemEOF:
+			return doc
+		case itemKey:
+			p.backup()
+			kv := p.keyValue()
+			if kvs == nil {
+				doc.Nodes = append(doc.Nodes, kv)
+				continue
+			}
+			*kvs = append(*kvs, kv)
+		case itemLeftBracket:
+			t := &TableNode{NodeType: NodeTable, Pos: token.pos, line: line(token.line)}
+			t.Key = p.key()
+			p.expect(itemRightBracket, "table header")
+			doc.Nodes = append(doc.Nodes, t)
+			kvs = &t.KeyValues
+		case itemDoubleLeftBracket:
+			t := &ArrayTableNode{NodeType: NodeArrayTable, Pos: token.pos, line: line(token.line)}
+			t.Key = p.key()
+			p.expect(itemDoubleRightBracket, "array of tables header")
+			doc.Nodes = append(doc.Nodes, t)
+			kvs = &t.KeyValues
+		default:
+			p.unexpected(token, "document")
+		}
+	}
+}
+
+// key parses a simple or dotted key.
+//
+//	key = simple-key / dotted-key
+func (p *parser) key() *KeyNode {
+	token := p.expect(itemKey, "key")
+	k := &KeyNode{NodeType: NodeKey, Pos: token.pos, line: line(token.line), Parts: []string{token.val}}
+	for p.peek().typ == itemDot {
+		p.next()
+		k.Parts = append(k.Parts, p.expect(itemKey, "dotted key").val)
+	}
+	return k
+}
+
+// keyValue parses a key/value pair.
+//
+//	keyval = key keyval-sep val
+func (p *parser) keyValue() *KeyValueNode {
+	k := p.key()
+	p.expect(itemEqual, "key/value pair")
+	return &KeyValueNode{NodeType: NodeKeyValue, Pos: k.Pos, line: k.line, Key: k, Value: p.value()}
+}
+
+// value parses a value.
+//
+//	val = string / boolean / array / inline-table / date-time / float / integer
+func (p *parser) value() Node {
+	switch token := p.next(); token.typ {
+	case itemStringValue:
+		return &StringNode{NodeType: NodeString, Pos: token.pos, line: line(token.line), Value: token.val}
+	case itemIntegerValue:
+		return p.integer(token)
+	case itemFloatValue:
+		return p.float(token)
+	case itemBooleanValue:
+		return &BoolNode{NodeType: NodeBool, Pos: token.pos, line: line(token.line), Value: token.val == "true"}
+	case itemTimeValue:
+		return p.dateTime(token)
+	case itemArrayStart:
+		return p.array(token)
+	case itemInlineTableStart:
+		return p.inlineTable(token)
+	default:
+		p.unexpected(token, "value")
+	}
+	return nil
+}
+
+// array parses an array. The opening bracket has already been consumed.
+//
+//	array = array-open [ array-values ] ws-comment-newline array-close
+func (p *parser) array(start item) *ArrayNode {
+	a := &ArrayNode{NodeType: NodeArray, Pos: start.pos, line: line(start.line)}
+	for {
+		if p.peek().typ == itemArrayEnd {
+			p.next()
+			return a
+		}
+		a.Values = append(a.Values, p.value())
+		switch token := p.next(); token.typ {
+		case itemComma:
+		case itemArrayEnd:
+			return a
+		default:
+			p.unexpected(token, "array")
+		}
+	}
+}
+
+// inlineTable parses an inline table. The opening brace has already been consumed.
+//
+//	inline-table = inline-table-open [ inline-table-keyvals ] inline-table-close
+func (p *parser) inlineTable(start item) *InlineTableNode {
+	t := &InlineTableNode{NodeType: NodeInlineTable, Pos: start.pos, line: line(start.line)}
+	if p.peek().typ == itemInlineTableEnd {
+		p.next()
+		return t
+	}
+	for {
+		t.KeyValues = append(t.KeyValues, p.keyValue())
+		switch token := p.next(); token.typ {
+		case itemComma:
+		case itemInlineTableEnd:
+			return t
+		default:
+			p.unexpected(token, "inline table")
+		}
+	}
+}
+
+func (p *parser) integer(token item) *IntegerNode {
+	v, err := parseInteger(token.val)
+	if err != nil {
+		p.errorf("%s", err)
+	}
+	return &IntegerNode{NodeType: NodeInteger, Pos: token.pos, line: line(token.line), Text: token.val, Value: v}
+}
+
+func (p *parser) float(token item) *FloatNode {
+	v, err := parseFloat(token.val)
+	if err != nil {
+		p.errorf("%s", err)
+	}
+	return &FloatNode{NodeType: NodeFloat, Pos: token.pos, line: line(token.line), Text: token.val, Value: v}
+}
+
+// Layouts of the date-time forms. The lexer has already validated the
+// values, they are only normalized before parsing.
+const (
+	layoutLocalDate = "2006-01-02"
+	layoutLocalTime = "15:04:05.999999999"
+)
+
+func (p *parser) dateTime(token item) *DateTimeNode {
+	n := &DateTimeNode{NodeType: NodeDateTime, Pos: token.pos, line: line(token.line), Text: token.val}
+	s := strings.ToUpper(token.val)
+	var (
+		v   time.Time
+		err error
+	)
+	switch {
+	case len(s) > 2 && s[2] == ':':
+		n.Kind = KindLocalTime
+		v, err = time.Parse(layoutLocalTime, s)
+	case len(s) == len(layoutLocalDate):
+		n.Kind = KindLocalDate
+		v, err = time.Parse(layoutLocalDate, s)
+	default:
+		// The delimiter between date and time may be a space.
+		s = s[:10] + "T" + s[11:]
+		if strings.ContainsAny(s[19:], "Z+-") {
+			n.Kind = KindOffsetDateTime
+			// Parse in UTC, time.Parse would use time.Local for an offset
+			// that matches it and the date-time would look like a local one.
+			v, err = time.ParseInLocation(time.RFC3339Nano, s, time.UTC)
+		} else {
+			n.Kind = KindLocalDateTime
+			v, err = time.Parse(layoutLocalDate+"T"+layoutLocalTime, s)
+		}
+	}
+	if err != nil {
+		p.errorf("invalid %s %s: %v", n.Kind, token.val, err)
+	}
+	n.Value = v
+	return n
+}
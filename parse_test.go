@@ -0,0 +1,295 @@
+package toml
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type parseTest struct {
+	name   string
+	input  string
+	ok     bool
+	result string // the String of the parsed document
+}
+
+const (
+	noError  = true
+	hasError = false
+)
+
+var parseTests = []parseTest{
+	{"empty", "", noError, ``},
+	{"comment", "# comment\n", noError, ``},
+	{"key/value", `key = "value"`, noError, `key = "value"`},
+	{"key/values", "a = 1\nb = 2.5\nc = true\nd = 1979-05-27", noError, "a = 1\nb = 2.5\nc = true\nd = 1979-05-27"},
+	{"quoted keys", `"a b" = 1` + "\n" + `'c' = 2`, noError, "\"a b\" = 1\nc = 2"},
+	{"dotted key", `a . "b.c" . d = "e"`, noError, `a."b.c".d = "e"`},
+	{"escaped string", `key = "tab\there\u0001"`, noError, `key = "tab\there\u0001"`},
+	{"literal string", `key = 'C:\Users'`, noError, `key = "C:\\Users"`},
+	{"table", "[table]\nkey = 1", noError, "[table]\nkey = 1"},
+	{"tables", "a = 1\n[b]\nc = 2\n[d.e]\nf = 3", noError, "a = 1\n[b]\nc = 2\n[d.e]\nf = 3"},
+	{"empty table", "[a]\n[b]", noError, "[a]\n[b]"},
+	{"array of tables", "[[p]]\nname = 'a'\n[[p]]\n[[p]]\nname = 'b'", noError, "[[p]]\nname = \"a\"\n[[p]]\n[[p]]\nname = \"b\""},
+	{"array", "a = [ 1, 2, [ 'x' ], ]", noError, `a = [1, 2, ["x"]]`},
+	{"empty array", "a = []", noError, `a = []`},
+	{"inline table", "a = { x = 1, y.z = { } }", noError, `a = { x = 1, y.z = {} }`},
+	{"array of inline tables", "a = [ { x = 1 }, { y = 2 } ]", noError, `a = [{ x = 1 }, { y = 2 }]`},
+	// Errors.
+	{"missing value", "a =", hasError, ``},
+	{"missing equal", "a", hasError, ``},
+	{"missing key", "= 1", hasError, ``},
+	{"unclosed table", "[a", hasError, ``},
+	{"mismatched table", "[a]]", hasError, ``},
+	{"mismatched array of tables", "[[a]", hasError, ``},
+	{"empty table header", "[]", hasError, ``},
+	{"value in table header", "[a = 1]", hasError, ``},
+	{"integer overflow", "a = 9223372036854775808", hasError, ``},
+	{"integer underflow", "a = -9223372036854775809", hasError, ``},
+	{"hex overflow", "a = 0xffffffffffffffff", hasError, ``},
+	{"float overflow", "a = 1e1000", hasError, ``},
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range parseTests {
+		doc, err := Parse(test.name, test.input)
+		switch {
+		case err == nil && !test.ok:
+			t.Errorf("%q: expected error; got none", test.name)
+			continue
+		case err != nil && test.ok:
+			t.Errorf("%q: unexpected error: %v", test.name, err)
+			continue
+		case err != nil && !test.ok:
+			// expected error, got one
+			continue
+		}
+		if result := doc.String(); result != test.result {
+			t.Errorf("%s=(%q): got\n\t%v\nexpected\n\t%v", test.name, test.input, result, test.result)
+		}
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	const input = `
+int1 = +99
+int2 = -17
+int3 = 1_000
+hex = 0xDEAD_beef
+oct = 0o755
+bin = 0b1101
+flt1 = -3.1415
+flt2 = 6.626e-34
+flt3 = -inf
+flt4 = nan
+bool = false
+str = "\u00E9"
+odt = 1979-05-27T00:32:00.999-07:00
+ldt = 1979-05-27 07:32:00
+ld = 1979-05-27
+lt = 00:32:00.5
+`
+	doc, err := Parse("values", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make(map[string]interface{})
+	kinds := make(map[string]DateTimeKind)
+	for _, n := range doc.Nodes {
+		kv := n.(*KeyValueNode)
+		key := kv.Key.String()
+		switch v := kv.Value.(type) {
+		case *IntegerNode:
+			values[key] = v.Value
+		case *FloatNode:
+			values[key] = v.Value
+		case *BoolNode:
+			values[key] = v.Value
+		case *StringNode:
+			values[key] = v.Value
+		case *DateTimeNode:
+			values[key] = v.Value
+			kinds[key] = v.Kind
+		default:
+			t.Fatalf("unexpected node %T for %s", v, key)
+		}
+	}
+	want := map[string]interface{}{
+		"int1": int64(99),
+		"int2": int64(-17),
+		"int3": int64(1000),
+		"hex":  int64(0xdeadbeef),
+		"oct":  int64(0755),
+		"bin":  int64(13),
+		"flt1": -3.1415,
+		"flt2": 6.626e-34,
+		"flt3": math.Inf(-1),
+		"bool": false,
+		"str":  "é",
+		"odt":  time.Date(1979, 5, 27, 0, 32, 0, 999000000, time.FixedZone("", -7*60*60)),
+		"ldt":  time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		"ld":   time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC),
+		"lt":   time.Date(0, 1, 1, 0, 32, 0, 500000000, time.UTC),
+	}
+	for key, w := range want {
+		got := values[key]
+		if wt, ok := w.(time.Time); ok {
+			if gt, ok := got.(time.Time); !ok || !gt.Equal(wt) {
+				t.Errorf("%s: got %v, expected %v", key, got, w)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("%s: got %#v, expected %#v", key, got, w)
+		}
+	}
+	if f, _ := values["flt4"].(float64); !math.IsNaN(f) {
+		t.Errorf("flt4: got %v, expected NaN", values["flt4"])
+	}
+	wantKinds := map[string]DateTimeKind{
+		"odt": KindOffsetDateTime,
+		"ldt": KindLocalDateTime,
+		"ld":  KindLocalDate,
+		"lt":  KindLocalTime,
+	}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("got kinds %v, expected %v", kinds, wantKinds)
+	}
+}
+
+func TestParsePos(t *testing.T) {
+	const input = "a = 'x'\n[t]\nb = [1, { c = \"y\" }]\n"
+	doc, err := Parse("pos", input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl := doc.Nodes[1].(*TableNode)
+	arr := tbl.KeyValues[0].Value.(*ArrayNode)
+	inline := arr.Values[1].(*InlineTableNode)
+	for _, test := range []struct {
+		node Node
+		pos  Pos
+		line int
+	}{
+		{doc.Nodes[0], 0, 1},
+		{doc.Nodes[0].(*KeyValueNode).Value, 4, 1},
+		{tbl, 8, 2},
+		{tbl.Key, 9, 2},
+		{tbl.KeyValues[0], 12, 3},
+		{arr, 16, 3},
+		{arr.Values[0], 17, 3},
+		{inline, 20, 3},
+		{inline.KeyValues[0].Value, 26, 3},
+	} {
+		if got := test.node.Position(); got != test.pos {
+			t.Errorf("%s: got pos %d, expected %d", test.node, got, test.pos)
+		}
+		if got := test.node.Line(); got != test.line {
+			t.Errorf("%s: got line %d, expected %d", test.node, got, test.line)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"a = 1\n[b\nc = 1", "toml: err:2:3: expected `]` after key in table header: `U+000A`"},
+		{"a = 1\nb =", `toml: err:2:4: invalid unspecified value`},
+		{"a = 1\nb = 99999999999999999999", `toml: err:2:5: invalid integer 99999999999999999999: value out of range`},
+		{"key = 01234", `toml: err:1:7: leading zeros are not allowed in a decimal integer`},
+		{"key = 00.5", `toml: err:1:7: leading zeros are not allowed in the integer part of a float`},
+		{"a = [1,\n2,\n3 = 4]", "toml: err:3:3: expected `,` or `]` after array element: `U+003D '='`"},
+		{"enabled = truex", "toml: err:1:15: expected newline after value: `U+0078 'x'`"},
+		{"enabled = t", "toml: err:1:11: expected `true` or `false`"},
+		{"f = nanx", "toml: err:1:8: expected newline after value: `U+0078 'x'`"},
+		{"f = i", "toml: err:1:5: expected `inf` or `nan`"},
+		{"port = 80abc", "toml: err:1:10: expected newline after value: `U+0061 'a'`"},
+		{"a = 1 b = 2", "toml: err:1:7: expected newline after value: `U+0062 'b'`"},
+		{"a = 'x' \t# ok\nb = \"y\" c = 1", "toml: err:2:9: expected newline after value: `U+0063 'c'`"},
+		{"a = [1] b = 2", "toml: err:1:9: expected newline after value: `U+0062 'b'`"},
+		{"a = {} b = 2", "toml: err:1:8: expected newline after value: `U+0062 'b'`"},
+		{"d = 1979-05-27 x", "toml: err:1:16: expected newline after value: `U+0078 'x'`"},
+		{"s = @", "toml: err:1:5: invalid character in value: `U+0040 '@'`"},
+		{"[a] b = 1", "toml: err:1:5: expected newline after table header: `U+0062 'b'`"},
+		{"[[a]] # ok\n[[b]]c = 1", "toml: err:2:6: expected newline after table header: `U+0063 'c'`"},
+		{"[a b]", "toml: err:1:4: expected `]` after key in table header: `U+0062 'b'`"},
+		{"[\na]\n", "toml: err:1:2: expected key in table header: `U+000A`"},
+		{"[ # c\n a]\n", "toml: err:1:3: expected key in table header: `U+0023 '#'`"},
+		{"[[ #x\nb]]\n", "toml: err:1:4: expected key in table header: `U+0023 '#'`"},
+		{"[]", "toml: err:1:2: expected key in table header: `U+005D ']'`"},
+		{"a = {b", "toml: err:1:7: expected `=` after key in inline table: EOF"},
+		{"a.", "toml: err:1:3: expected key after `.`: EOF"},
+		{"key\n= 1", "toml: err:1:4: expected `=` after key: `U+000A`"},
+		{"a b = 1", "toml: err:1:3: expected `=` after key: `U+0062 'b'`"},
+		{"key", "toml: err:1:4: expected `=` after key: EOF"},
+	} {
+		_, err := Parse("err", test.input)
+		if err == nil {
+			t.Errorf("%q: expected error", test.input)
+			continue
+		}
+		if got := err.Error(); got != test.want {
+			t.Errorf("%q: got error\n\t%s\nexpected\n\t%s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseRedefinition(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		want  string
+	}{
+		{"timeout = 1\nport = 2\ntimeout = 3", "3:1: key timeout is already defined as a value at line 1, column 1"},
+		{"[a]\nx = 1\n\n  [a]", "4:3: table a is already defined at line 1, column 1"},
+		{"[a.b]\n[a]\n[a]", "3:1: table a is already defined at line 2, column 1"},
+		{"[a]\nb.c = 1\n[a.b]", "3:1: table a.b is already defined at line 2, column 1"},
+		{"[a.b.c]\nz = 9\n[a]\nb.c.t = 1", "4:1: key a.b.c is already defined as a table at line 1, column 1"},
+		{"a = 1\n[a.b]", "2:1: key a is already defined as a value at line 1, column 1"},
+		{"a = 1\n[[a]]", "2:1: key a is already defined as a value at line 1, column 1"},
+		{"a = [1]\n[[a]]", "2:1: key a is already defined as an array at line 1, column 1"},
+		{"[a]\n[[a]]", "2:1: key a is already defined as a table at line 1, column 1"},
+		{"[[a]]\n[a]", "2:1: key a is already defined as an array of tables at line 1, column 1"},
+		{"[[a.b]]\n[a]\nb.y = 2", "3:1: key a.b is already defined as an array of tables at line 1, column 1"},
+		{"[[a]]\n[a.b]\n[a.b]", "3:1: table a.b is already defined at line 2, column 1"},
+		{"t = { x = 1 }\nt.y = 2", "2:1: key t is already defined as an inline table at line 1, column 1"},
+		{"t = { x = 1 }\n[t]", "2:1: key t is already defined as an inline table at line 1, column 1"},
+		{"t = { x = 1 }\n[t.u]", "2:1: key t is already defined as an inline table at line 1, column 1"},
+		{"t = { x = 1, x = 2 }", "1:14: key t.x is already defined as a value at line 1, column 7"},
+		{"t = { u = { x = 1 }, u.y = 2 }", "1:22: key t.u is already defined as an inline table at line 1, column 7"},
+		{"a = [{ x = 1, x = 2 }]", "1:15: key a.x is already defined as a value at line 1, column 8"},
+		{"a.b = 1\na.b.c = 2", "2:1: key a.b is already defined as a value at line 1, column 1"},
+	} {
+		_, err := Parse("err", test.input)
+		if err == nil {
+			t.Errorf("%q: expected error", test.input)
+			continue
+		}
+		if got, want := err.Error(), "toml: err:"+test.want; got != want {
+			t.Errorf("%q: got error\n\t%s\nexpected\n\t%s", test.input, got, want)
+		}
+	}
+	for _, input := range []string{
+		"[a.b]\n[a]",
+		"[a]\nb.c = 1\n[a.b.d]",
+		"[a.b.c]\n[a]\nb.d = 1",
+		"a.b = 1\na.c = 2",
+		"[[a]]\n[a.b]\n[[a]]\n[a.b]",
+		"t = { u.x = 1, u.y = 2 }",
+	} {
+		if _, err := Parse("ok", input); err != nil {
+			t.Errorf("%q: %v", input, err)
+		}
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse("bench", benchInput); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
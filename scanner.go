@@ -0,0 +1,201 @@
+package toml
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A TokenKind identifies the kind of a Token.
+type TokenKind int
+
+const (
+	TokenError            = TokenKind(itemError)              // An error; Value holds its description.
+	TokenEOF              = TokenKind(itemEOF)                // The end of the input.
+	TokenKey              = TokenKind(itemKey)                // A bare or quoted key, or a part of a dotted key.
+	TokenDot              = TokenKind(itemDot)                // The dot separating the parts of a dotted key.
+	TokenEqual            = TokenKind(itemEqual)              // The equals sign between a key and its value.
+	TokenTableStart       = TokenKind(itemLeftBracket)        // '[' opening a table header.
+	TokenTableEnd         = TokenKind(itemRightBracket)       // ']' closing a table header.
+	TokenArrayTableStart  = TokenKind(itemDoubleLeftBracket)  // '[[' opening an array of tables header.
+	TokenArrayTableEnd    = TokenKind(itemDoubleRightBracket) // ']]' closing an array of tables header.
+	TokenArrayStart       = TokenKind(itemArrayStart)         // '[' opening an array value.
+	TokenArrayEnd         = TokenKind(itemArrayEnd)           // ']' closing an array value.
+	TokenInlineTableStart = TokenKind(itemInlineTableStart)   // '{' opening an inline table value.
+	TokenInlineTableEnd   = TokenKind(itemInlineTableEnd)     // '}' closing an inline table value.
+	TokenComma            = TokenKind(itemComma)              // ',' separating array elements or inline table key/value pairs.
+	TokenString           = TokenKind(itemStringValue)        // A string value.
+	TokenInteger          = TokenKind(itemIntegerValue)       // An integer value.
+	TokenFloat            = TokenKind(itemFloatValue)         // A float value.
+	TokenBool             = TokenKind(itemBooleanValue)       // A boolean value.
+	TokenDateTime         = TokenKind(itemTimeValue)          // A date-time, date or time value.
+	TokenComment          = TokenKind(itemComment)            // A comment, without the newline.
+	TokenNewline          = TokenKind(itemNewline)            // A newline, LF or CRLF.
+	TokenWhitespace       = TokenKind(itemWhitespace)         // A run of spaces and tabs.
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TokenError:            "Error",
+	TokenEOF:              "EOF",
+	TokenKey:              "Key",
+	TokenDot:              "Dot",
+	TokenEqual:            "Equal",
+	TokenTableStart:       "TableStart",
+	TokenTableEnd:         "TableEnd",
+	TokenArrayTableStart:  "ArrayTableStart",
+	TokenArrayTableEnd:    "ArrayTableEnd",
+	TokenArrayStart:       "ArrayStart",
+	TokenArrayEnd:         "ArrayEnd",
+	TokenInlineTableStart: "InlineTableStart",
+	TokenInlineTableEnd:   "InlineTableEnd",
+	TokenComma:            "Comma",
+	TokenString:           "String",
+	TokenInteger:          "Integer",
+	TokenFloat:            "Float",
+	TokenBool:             "Bool",
+	TokenDateTime:         "DateTime",
+	TokenComment:          "Comment",
+	TokenNewline:          "Newline",
+	TokenWhitespace:       "Whitespace",
+}
+
+func (k TokenKind) String() string {
+	if s, ok := tokenKindNames[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// A StringStyle is the way a string or a quoted key is written.
+type StringStyle int
+
+const (
+	NotQuoted              StringStyle = iota // not a string, or a bare key
+	BasicString                               // "basic string"
+	LiteralString                             // 'literal string'
+	MultiLineBasicString                      // """multi-line basic string"""
+	MultiLineLiteralString                    // '''multi-line literal string'''
+)
+
+// A Token is a lexical token of a TOML document.
+type Token struct {
+	Kind   TokenKind
+	Raw    string // the text of the token as written in the document
+	Value  string // the value of the token: strings and quoted keys are unescaped
+	Start  Pos    // byte position of the start of the token
+	End    Pos    // byte position after the end of the token
+	Line   int    // line number of the start of the token, starting at 1
+	Column int    // column number in runes of the start of the token, starting at 1
+
+	Style       StringStyle // how a TokenString or a TokenKey is quoted
+	Base        int         // the base of a TokenInteger: 2, 8, 10 or 16
+	DigitGroups []int       // the number of digits between the underscores of a TokenInteger, nil if there are none
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%d:%d: %s %q", t.Line, t.Column, t.Kind, t.Raw)
+}
+
+// A ScanMode controls the tokens returned by a Scanner.
+type ScanMode uint
+
+const (
+	ScanTrivia ScanMode = 1 << iota // return comments, newlines and whitespace
+)
+
+// A Scanner splits a TOML document into tokens. It checks the lexical
+// structure of the document, but not whether keys are defined twice.
+type Scanner struct {
+	lex *lexer
+
+	// The line, position and column of the previous token, to count the
+	// column of the next one from there rather than from its line start.
+	line   int
+	pos    Pos
+	column int
+}
+
+// NewScanner returns a new Scanner of src. The name is used only for
+// error reports.
+func NewScanner(name, src string, mode ScanMode) *Scanner {
+	l := lex(name, src)
+	l.options.emitTrivia = mode&ScanTrivia != 0
+	return &Scanner{lex: l}
+}
+
+// Next returns the next token of the document. Once a TokenEOF or
+// TokenError has been returned, it returns TokenEOF.
+//
+// The Value of a TokenError is the description of the error, and the
+// error is at Start. Use Err for the error with its position.
+func (s *Scanner) Next() Token {
+	input := s.lex.input
+	it := s.lex.nextItem()
+	t := Token{
+		Kind:   TokenKind(it.typ),
+		Raw:    input[it.pos:it.end],
+		Value:  it.val,
+		Start:  it.pos,
+		End:    it.end,
+		Line:   it.line,
+		Column: s.columnOf(it),
+	}
+	switch it.style {
+	case styleBasic, styleLiteral, styleMultiLineBasic, styleMultiLineLiteral:
+		t.Style = StringStyle(it.style-styleBasic) + BasicString
+	case styleDecimal, styleHex, styleOctal, styleBinary:
+		t.Base = integerBase(it.style)
+		t.DigitGroups = digitGroups(t.Raw)
+	}
+	return t
+}
+
+// columnOf returns the column of it, counting on from the previous token if
+// it is on the same line.
+func (s *Scanner) columnOf(it item) int {
+	if it.line == s.line && it.pos >= s.pos {
+		s.column += utf8.RuneCountInString(s.lex.input[s.pos:it.pos])
+	} else {
+		s.column = column(s.lex.input, int(it.pos))
+	}
+	s.line, s.pos = it.line, it.pos
+	return s.column
+}
+
+// integerBase returns the base of an integer written in the given style.
+func integerBase(style valueStyle) int {
+	switch style {
+	case styleHex:
+		return 16
+	case styleOctal:
+		return 8
+	case styleBinary:
+		return 2
+	}
+	return 10
+}
+
+// digitGroups returns the number of digits between the underscores of the
+// integer raw, or nil if it has no underscores.
+func digitGroups(raw string) []int {
+	if !strings.Contains(raw, "_") {
+		return nil
+	}
+	raw = strings.TrimLeft(raw, "+-")
+	if len(raw) > 2 && raw[0] == '0' && (raw[1] == 'x' || raw[1] == 'o' || raw[1] == 'b') {
+		raw = raw[2:]
+	}
+	var groups []int
+	for _, g := range strings.Split(raw, "_") {
+		groups = append(groups, len(g))
+	}
+	return groups
+}
+
+// Err returns the error of the TokenError t as a *SyntaxError.
+func (s *Scanner) Err(t Token) error {
+	if t.Kind != TokenError {
+		return nil
+	}
+	return newSyntaxError(s.lex.name, s.lex.input, t.Start, t.Line, t.Value)
+}
@@ -0,0 +1,144 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	input := "# c\na = \"ʞ\\n\" \r\n[t]"
+	want := []Token{
+		{TokenComment, "# c", "# c", 0, 3, 1, 1, NotQuoted, 0, nil},
+		{TokenNewline, "\n", "\n", 3, 4, 1, 4, NotQuoted, 0, nil},
+		{TokenKey, "a", "a", 4, 5, 2, 1, NotQuoted, 0, nil},
+		{TokenWhitespace, " ", " ", 5, 6, 2, 2, NotQuoted, 0, nil},
+		{TokenEqual, "=", "=", 6, 7, 2, 3, NotQuoted, 0, nil},
+		{TokenWhitespace, " ", " ", 7, 8, 2, 4, NotQuoted, 0, nil},
+		{TokenString, "\"ʞ\\n\"", "ʞ\n", 8, 14, 2, 5, BasicString, 0, nil},
+		{TokenWhitespace, " ", " ", 14, 15, 2, 10, NotQuoted, 0, nil},
+		{TokenNewline, "\r\n", "\r\n", 15, 17, 2, 11, NotQuoted, 0, nil},
+		{TokenTableStart, "[", "[", 17, 18, 3, 1, NotQuoted, 0, nil},
+		{TokenKey, "t", "t", 18, 19, 3, 2, NotQuoted, 0, nil},
+		{TokenTableEnd, "]", "]", 19, 20, 3, 3, NotQuoted, 0, nil},
+		{TokenEOF, "", "", 20, 20, 3, 4, NotQuoted, 0, nil},
+	}
+	s := NewScanner("", input, ScanTrivia)
+	for _, w := range want {
+		if got := s.Next(); !reflect.DeepEqual(got, w) {
+			t.Errorf("got %+v, expected %+v", got, w)
+		}
+	}
+	if got := s.Next(); got.Kind != TokenEOF {
+		t.Errorf("got %v after the end, expected EOF", got)
+	}
+}
+
+func TestScannerStyle(t *testing.T) {
+	input := `'a' = [1_000_000, -1_0, 0xdead_beef, 0o7, 0b1_1, "b", """c""", '''d''']`
+	var got []string
+	s := NewScanner("", input, 0)
+	for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+		switch tok.Kind {
+		case TokenKey, TokenString:
+			got = append(got, fmt.Sprintf("%s %d", tok.Raw, tok.Style))
+		case TokenInteger:
+			got = append(got, fmt.Sprintf("%s %d %v", tok.Raw, tok.Base, tok.DigitGroups))
+		}
+	}
+	want := []string{
+		"'a' 2",
+		"1_000_000 10 [1 3 3]",
+		"-1_0 10 [1 1]",
+		"0xdead_beef 16 [4 4]",
+		"0o7 8 []",
+		"0b1_1 2 [1 1]",
+		`"b" 1`,
+		`"""c""" 3`,
+		"'''d''' 4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n\t%q\nexpected\n\t%q", got, want)
+	}
+}
+
+func TestScannerSkipsTrivia(t *testing.T) {
+	s := NewScanner("", "a = 1 # one\n", 0)
+	var kinds []string
+	for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+		kinds = append(kinds, tok.Kind.String())
+	}
+	if got, want := strings.Join(kinds, " "), "Key Equal Integer"; got != want {
+		t.Errorf("got %s, expected %s", got, want)
+	}
+}
+
+func TestScannerRaw(t *testing.T) {
+	for _, input := range []string{
+		benchInput,
+		"'a' . \"b\\\"c\" = '''\nx''''' # ''' \n",
+		"s = \"\"\"\\\n  a\"\"\"\"\"\n",
+		"a = [ 1, # one\n\t2.5e3, 1979-05-27, { x = true } ]\n[[b]]\n",
+	} {
+		s := NewScanner("", input, ScanTrivia)
+		var b strings.Builder
+		for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+			if tok.Kind == TokenError {
+				t.Fatalf("%q: %v", input, s.Err(tok))
+			}
+			if input[tok.Start:tok.End] != tok.Raw {
+				t.Errorf("%q: token %v does not span %d to %d", input, tok, tok.Start, tok.End)
+			}
+			b.WriteString(tok.Raw)
+		}
+		if got := b.String(); got != input {
+			t.Errorf("got\n%s\nexpected\n%s", got, input)
+		}
+	}
+}
+
+func TestScannerColumn(t *testing.T) {
+	input := "a = [\"ʞ\", 'é', \"\"\"x\ny\"\"\" , { b = 1 }]\n\"ü\".c = 2 # ß\nd = '''\né''' "
+	for _, mode := range []ScanMode{0, ScanTrivia} {
+		s := NewScanner("", input, mode)
+		for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+			if tok.Kind == TokenError {
+				t.Fatal(s.Err(tok))
+			}
+			if want := column(input, int(tok.Start)); tok.Column != want {
+				t.Errorf("%v: got column %d, expected %d", tok, tok.Column, want)
+			}
+		}
+	}
+}
+
+func TestScannerError(t *testing.T) {
+	s := NewScanner("x.toml", "a = 1\nb = [1 2]", 0)
+	var tok Token
+	for tok = s.Next(); tok.Kind != TokenEOF && tok.Kind != TokenError; tok = s.Next() {
+	}
+	if tok.Kind != TokenError || tok.Line != 2 || tok.Column != 8 {
+		t.Errorf("got %v, expected an error at 2:8", tok)
+	}
+	want := "toml: x.toml:2:8: expected `,` or `]` after array element: `U+0032 '2'`"
+	if err := s.Err(tok); err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+	if err := s.Err(s.Next()); err != nil {
+		t.Errorf("got error %v for EOF", err)
+	}
+}
+
+func BenchmarkScanLongLine(b *testing.B) {
+	input := "a = [" + strings.Repeat("12345, ", 40000) + "1]\n"
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		s := NewScanner("bench", input, 0)
+		for tok := s.Next(); tok.Kind != TokenEOF; tok = s.Next() {
+			if tok.Kind == TokenError {
+				b.Fatal(s.Err(tok))
+			}
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package toml
+
+import "fmt"
+
+// tableKind is how a table was defined. It decides how the table may be
+// extended later in the document.
+type tableKind int
+
+const (
+	tableImplicit tableKind = iota // created for the parent keys of a header; may still be defined once
+	tableHeader                    // defined by a [table] header or an [[array of tables]] element
+	tableDotted                    // defined by dotted keys; may get sub-tables from headers
+	tableInline                    // defined by an inline table; may not be extended
+)
+
+// table is a table assembled from the key/value pairs, table headers and
+// inline tables of a document.
+type table struct {
+	node   Node                   // the node that defined the table; nil for the root table
+	kind   tableKind              // how the table was defined
+	path   []string               // key path of the table
+	keys   []string               // keys in the order they were defined
+	values map[string]interface{} // Node, *table or *tableArray
+	defs   map[string]Node        // the node that defined each key
+}
+
+// tableArray is an array of tables defined by [[array of tables]] headers.
+type tableArray struct {
+	node   Node // the first header
+	tables []*table
+}
+
+func newTable(n Node, kind tableKind, path []string) *table {
+	return &table{
+		node:   n,
+		kind:   kind,
+		path:   path,
+		values: make(map[string]interface{}),
+		defs:   make(map[string]Node),
+	}
+}
+
+// child returns a new table for key of t.
+func (t *table) child(key string, n Node, kind tableKind) *table {
+	return newTable(n, kind, append(t.path[:len(t.path):len(t.path)], key))
+}
+
+// set sets the value of key defined by node n, keeping the order in which
+// keys were defined.
+func (t *table) set(key string, v interface{}, n Node) {
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = v
+	t.defs[key] = n
+}
+
+// A defineError reports a key or table of node n that was already defined
+// by node prev.
+type defineError struct {
+	n, prev Node
+	msg     string
+}
+
+func (e *defineError) Error() string {
+	return fmt.Sprintf("toml: line %d: %s at line %d", e.n.Line(), e.msg, e.prev.Line())
+}
+
+// redefined returns an error for key of node n, which is already defined in t.
+func (t *table) redefined(n Node, key string) error {
+	path := formatKey(append(t.path[:len(t.path):len(t.path)], key))
+	if _, ok := n.(*TableNode); ok {
+		if v, ok := t.values[key].(*table); ok && v.kind != tableInline {
+			return &defineError{n, t.defs[key], "table " + path + " is already defined"}
+		}
+	}
+	return &defineError{n, t.defs[key], fmt.Sprintf("key %s is already defined as %s", path, describeDefinition(t.values[key]))}
+}
+
+func describeDefinition(v interface{}) string {
+	switch v := v.(type) {
+	case *table:
+		if v.kind == tableInline {
+			return "an inline table"
+		}
+		return "a table"
+	case *tableArray:
+		return "an array of tables"
+	case *ArrayNode:
+		return "an array"
+	}
+	return "a value"
+}
+
+// buildTable assembles the root table of doc. It reports an error if the
+// document defines a key or a table twice.
+//
+// A table may be defined by a header only once. The tables created for the
+// parent keys of a header may still be defined by a header later, or be
+// extended by dotted keys. A table defined by dotted keys may get sub-tables
+// from headers, but may not be defined by a header itself. Inline tables
+// and arrays may not be extended at all.
+func buildTable(doc *Document) (*table, error) {
+	root := newTable(nil, tableHeader, nil)
+	cur := root
+	for _, n := range doc.Nodes {
+		var err error
+		switch n := n.(type) {
+		case *KeyValueNode:
+			err = cur.setKeyValue(n)
+		case *TableNode:
+			cur, err = root.defineTable(n.Key.Parts, n)
+			if err == nil {
+				err = cur.setKeyValues(n.KeyValues)
+			}
+		case *ArrayTableNode:
+			cur, err = root.appendTable(n.Key.Parts, n)
+			if err == nil {
+				err = cur.setKeyValues(n.KeyValues)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// descend returns the parent table of the header n with the dotted key
+// parts, creating any tables that are not defined yet. If a key refers to
+// an array of tables, its last element is used.
+func (t *table) descend(parts []string, n Node) (*table, error) {
+	for _, part := range parts[:len(parts)-1] {
+		switch v := t.values[part].(type) {
+		case nil:
+			next := t.child(part, n, tableImplicit)
+			t.set(part, next, n)
+			t = next
+			continue
+		case *table:
+			if v.kind != tableInline {
+				t = v
+				continue
+			}
+		case *tableArray:
+			t = v.tables[len(v.tables)-1]
+			continue
+		}
+		return nil, t.redefined(n, part)
+	}
+	return t, nil
+}
+
+// defineTable defines the table of the [table] header n with the dotted key parts.
+func (t *table) defineTable(parts []string, n *TableNode) (*table, error) {
+	parent, err := t.descend(parts, n)
+	if err != nil {
+		return nil, err
+	}
+	key := parts[len(parts)-1]
+	switch v := parent.values[key].(type) {
+	case nil:
+		next := parent.child(key, n, tableHeader)
+		parent.set(key, next, n)
+		return next, nil
+	case *table:
+		if v.kind == tableImplicit {
+			v.node, v.kind = n, tableHeader
+			parent.defs[key] = n
+			return v, nil
+		}
+	}
+	return nil, parent.redefined(n, key)
+}
+
+// appendTable appends a new table to the array of tables at the dotted key parts.
+func (t *table) appendTable(parts []string, n *ArrayTableNode) (*table, error) {
+	parent, err := t.descend(parts, n)
+	if err != nil {
+		return nil, err
+	}
+	key := parts[len(parts)-1]
+	next := parent.child(key, n, tableHeader)
+	switch v := parent.values[key].(type) {
+	case nil:
+		parent.set(key, &tableArray{node: n, tables: []*table{next}}, n)
+	case *tableArray:
+		v.tables = append(v.tables, next)
+	default:
+		return nil, parent.redefined(n, key)
+	}
+	return next, nil
+}
+
+func (t *table) setKeyValues(kvs []*KeyValueNode) error {
+	for _, kv := range kvs {
+		if err := t.setKeyValue(kv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setKeyValue sets the value of kv. A dotted key defines the tables
+// for each of its parts but the last.
+func (t *table) setKeyValue(kv *KeyValueNode) error {
+	parts := kv.Key.Parts
+	for _, part := range parts[:len(parts)-1] {
+		switch v := t.values[part].(type) {
+		case nil:
+			next := t.child(part, kv, tableDotted)
+			t.set(part, next, kv)
+			t = next
+			continue
+		case *table:
+			if v.kind == tableImplicit {
+				v.kind = tableDotted
+			}
+			if v.kind == tableDotted {
+				t = v
+				continue
+			}
+		}
+		return t.redefined(kv, part)
+	}
+	key := parts[len(parts)-1]
+	if _, ok := t.values[key]; ok {
+		return t.redefined(kv, key)
+	}
+	path := append(t.path[:len(t.path):len(t.path)], key)
+	var v interface{} = kv.Value
+	if it, ok := kv.Value.(*InlineTableNode); ok {
+		var err error
+		if v, err = buildInlineTable(it, path); err != nil {
+			return err
+		}
+	} else if a, ok := kv.Value.(*ArrayNode); ok {
+		if err := checkArray(a, path); err != nil {
+			return err
+		}
+	}
+	t.set(key, v, kv)
+	return nil
+}
+
+// buildInlineTable assembles the table of an inline table at the key path.
+func buildInlineTable(n *InlineTableNode, path []string) (*table, error) {
+	t := newTable(n, tableDotted, path)
+	if err := t.setKeyValues(n.KeyValues); err != nil {
+		return nil, err
+	}
+	t.kind = tableInline
+	return t, nil
+}
+
+// checkArray reports an error if an inline table in the array a at the key
+// path defines a key twice.
+// The tables of arrays are assembled when they are decoded.
+func checkArray(a *ArrayNode, path []string) error {
+	for _, n := range a.Values {
+		var err error
+		switch n := n.(type) {
+		case *InlineTableNode:
+			_, err = buildInlineTable(n, path)
+		case *ArrayNode:
+			err = checkArray(n, path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package toml_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	toml "github.com/Code-Hex/go-toml"
+	"github.com/Code-Hex/go-toml/internal/tag"
+)
+
+// knownFailures are the toml-test cases this package does not pass yet,
+// with the reason.
+var knownFailures = map[string]string{}
+
+// TestTOMLTest runs the toml-test cases in testdata/toml-test. A valid
+// document must decode to the value of its .json file and that value must
+// survive a round trip through the encoder, an invalid document must be
+// rejected.
+func TestTOMLTest(t *testing.T) {
+	files, err := tomlTestFiles("testdata/toml-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range files {
+		if strings.HasPrefix(name, "invalid/") {
+			runTOMLTest(t, "decode/"+name, name, decodeInvalid)
+			continue
+		}
+		runTOMLTest(t, "decode/"+name, name, decodeValid)
+		runTOMLTest(t, "encode/"+name, name, encodeValid)
+	}
+}
+
+// runTOMLTest runs the test f of the toml-test case name as a subtest.
+// A known failure is skipped, or reported if it passes.
+func runTOMLTest(t *testing.T, test, name string, f func(name string) error) {
+	t.Run(test, func(t *testing.T) {
+		err := f(name)
+		reason, known := knownFailures[test]
+		switch {
+		case known && err == nil:
+			t.Errorf("known failure passes, remove it from knownFailures")
+		case known:
+			t.Skip("known failure: " + reason)
+		case err != nil:
+			t.Error(err)
+		}
+	})
+}
+
+// tomlTestFiles returns the names of the test cases in dir, which are the
+// paths of their .toml files relative to dir without the extension.
+func tomlTestFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".toml" {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(strings.TrimSuffix(rel, ".toml")))
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+func readTOMLTest(name, ext string) ([]byte, error) {
+	return os.ReadFile(filepath.Join("testdata", "toml-test", filepath.FromSlash(name)+ext))
+}
+
+func readTOMLTestJSON(name string) (interface{}, error) {
+	b, err := readTOMLTest(name, ".json")
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// decodeTagged decodes the TOML document data into tagged JSON.
+func decodeTagged(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := toml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return tag.Add(v)
+}
+
+func decodeValid(name string) error {
+	input, err := readTOMLTest(name, ".toml")
+	if err != nil {
+		return err
+	}
+	want, err := readTOMLTestJSON(name)
+	if err != nil {
+		return err
+	}
+	got, err := decodeTagged(input)
+	if err != nil {
+		return fmt.Errorf("%+v", err)
+	}
+	if err := compareTagged(want, got, ""); err != nil {
+		return fmt.Errorf("%s\ninput:\n%s", err, input)
+	}
+	return nil
+}
+
+func decodeInvalid(name string) error {
+	input, err := readTOMLTest(name, ".toml")
+	if err != nil {
+		return err
+	}
+	if v, err := decodeTagged(input); err == nil {
+		return fmt.Errorf("expected error, decoded %v\ninput:\n%s", v, input)
+	}
+	return nil
+}
+
+func encodeValid(name string) error {
+	want, err := readTOMLTestJSON(name)
+	if err != nil {
+		return err
+	}
+	v, err := tag.Remove(want)
+	if err != nil {
+		return err
+	}
+	b, err := toml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	got, err := decodeTagged(b)
+	if err != nil {
+		return fmt.Errorf("%+v\nencoded:\n%s", err, b)
+	}
+	if err := compareTagged(want, got, ""); err != nil {
+		return fmt.Errorf("%s\nencoded:\n%s", err, b)
+	}
+	return nil
+}
+
+// compareTagged compares the tagged JSON values want and got the way
+// toml-test does: floats, integers and date-times are compared by value,
+// so that the formatting does not matter.
+func compareTagged(want, got interface{}, path string) error {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, expected table", path, got)
+		}
+		if typ, ok := w["type"].(string); ok && len(w) == 2 {
+			if value, ok := w["value"].(string); ok {
+				return compareValue(typ, value, g, path)
+			}
+		}
+		if len(w) != len(g) {
+			return fmt.Errorf("%s: got keys %v, expected %v", path, sortedKeys(g), sortedKeys(w))
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok {
+				return fmt.Errorf("%s: missing key %q", path, k)
+			}
+			if err := compareTagged(wv, gv, path+"."+k); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: got %T, expected array", path, got)
+		}
+		if len(w) != len(g) {
+			return fmt.Errorf("%s: got array of %d values, expected %d", path, len(g), len(w))
+		}
+		for i := range w {
+			if err := compareTagged(w[i], g[i], fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%s: unexpected JSON value %v", path, want)
+}
+
+func compareValue(typ, value string, got map[string]interface{}, path string) error {
+	gotType, _ := got["type"].(string)
+	gotValue, _ := got["value"].(string)
+	if typ != gotType {
+		return fmt.Errorf("%s: got %s %q, expected %s %q", path, gotType, gotValue, typ, value)
+	}
+	equal := value == gotValue
+	switch typ {
+	case "integer":
+		w, err1 := strconv.ParseInt(value, 10, 64)
+		g, err2 := strconv.ParseInt(gotValue, 10, 64)
+		equal = err1 == nil && err2 == nil && w == g
+	case "float":
+		w, err1 := strconv.ParseFloat(value, 64)
+		g, err2 := strconv.ParseFloat(gotValue, 64)
+		equal = err1 == nil && err2 == nil && (w == g || math.IsNaN(w) && math.IsNaN(g))
+	case "datetime":
+		w, err1 := tag.Remove(map[string]interface{}{"type": typ, "value": value})
+		g, err2 := tag.Remove(got)
+		equal = err1 == nil && err2 == nil && w.(time.Time).Equal(g.(time.Time))
+	case "datetime-local", "date-local", "time-local":
+		w, err1 := tag.Remove(map[string]interface{}{"type": typ, "value": value})
+		g, err2 := tag.Remove(got)
+		equal = err1 == nil && err2 == nil && w == g
+	}
+	if !equal {
+		return fmt.Errorf("%s: got %s %q, expected %q", path, typ, gotValue, value)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
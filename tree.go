@@ -0,0 +1,524 @@
+package toml
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// A Tree is a TOML table whose values are read and changed by their dotted
+// key paths, such as "server.tls.cert".
+//
+// The values of a Tree are the Go values Unmarshal stores in an empty
+// interface, except for tables, which are *Tree, and arrays, which are
+// []interface{} holding a *Tree for each table. An array of tables is an
+// array of *Tree.
+type Tree struct {
+	path    []string // key path of the table
+	keys    []string // keys in the order they were defined
+	entries map[string]*treeEntry
+}
+
+// treeEntry is a value of a Tree with the position of its definition. The
+// column is counted only when an error reports it.
+type treeEntry struct {
+	value interface{}
+	src   string // the document the value was parsed from
+	pos   int
+	line  int // 0 for a value that was not parsed
+}
+
+// NewTree returns an empty Tree.
+func NewTree() *Tree {
+	return newTree(nil)
+}
+
+func newTree(path []string) *Tree {
+	return &Tree{path: path, entries: make(map[string]*treeEntry)}
+}
+
+// ParseTree parses src as a TOML document and returns its root table. name
+// is used only in error reports. A syntax error is returned as a
+// *SyntaxError.
+func ParseTree(name, src string) (*Tree, error) {
+	doc, err := Parse(name, src)
+	if err != nil {
+		return nil, err
+	}
+	root, err := buildTable(doc)
+	if err != nil {
+		return nil, err
+	}
+	return treeOf(root, src)
+}
+
+// treeOf returns the Tree of t, a table of the document src.
+func treeOf(t *table, src string) (*Tree, error) {
+	tree := newTree(t.path)
+	for _, key := range t.keys {
+		v, err := treeValueOf(t.values[key], tree.keyPath(key), src)
+		if err != nil {
+			return nil, err
+		}
+		def := t.defs[key]
+		tree.keys = append(tree.keys, key)
+		tree.entries[key] = &treeEntry{
+			value: v,
+			src:   src,
+			pos:   int(def.Position()),
+			line:  def.Line(),
+		}
+	}
+	return tree, nil
+}
+
+// treeValueOf returns the value of a Tree for val at the key path, where val
+// is a Node, *table or *tableArray of the document src.
+func treeValueOf(val interface{}, path []string, src string) (interface{}, error) {
+	switch val := val.(type) {
+	case *table:
+		return treeOf(val, src)
+	case *tableArray:
+		a := make([]interface{}, len(val.tables))
+		for i, t := range val.tables {
+			var err error
+			if a[i], err = treeOf(t, src); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case *InlineTableNode:
+		t, err := buildInlineTable(val, path)
+		if err != nil {
+			return nil, err
+		}
+		return treeOf(t, src)
+	case *ArrayNode:
+		a := make([]interface{}, len(val.Values))
+		for i, n := range val.Values {
+			var err error
+			if a[i], err = treeValueOf(n, path, src); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case Node:
+		return scalarValue(val), nil
+	}
+	panic(fmt.Sprintf("toml: unexpected value %T", val))
+}
+
+// Keys returns the keys of t in the order they were defined.
+func (t *Tree) Keys() []string {
+	return append([]string(nil), t.keys...)
+}
+
+// lookup returns the table that holds the key path of t and the key in it.
+// The entry is nil if the key is not defined, which is an error if it is
+// required. It is an error if a parent key is not a table.
+func (t *Tree) lookup(path string, required bool) (parent *Tree, key string, e *treeEntry, err error) {
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	parent = t
+	for _, part := range parts[:len(parts)-1] {
+		pe := parent.entries[part]
+		if pe == nil {
+			parent = nil
+			break
+		}
+		sub, ok := pe.value.(*Tree)
+		if !ok {
+			return nil, "", nil, pe.typeError(parent.keyPath(part), "a table")
+		}
+		parent = sub
+	}
+	key = parts[len(parts)-1]
+	if parent != nil {
+		e = parent.entries[key]
+	}
+	if e == nil && required {
+		return parent, key, nil, fmt.Errorf("toml: key %s is not defined", formatKey(append(t.keyPath(parts[0]), parts[1:]...)))
+	}
+	return parent, key, e, nil
+}
+
+// Get returns the value of the key path, or nil if it is not defined.
+func (t *Tree) Get(path string) interface{} {
+	_, _, e, _ := t.lookup(path, false)
+	if e == nil {
+		return nil
+	}
+	return e.value
+}
+
+// Has reports whether the key path is defined.
+func (t *Tree) Has(path string) bool {
+	_, _, e, _ := t.lookup(path, false)
+	return e != nil
+}
+
+// Set sets the value of the key path, creating the tables of its parent
+// keys as needed. A new key is added after the keys of its table.
+//
+// The value is converted to a value of a Tree: Go integers become int64,
+// floats float64, slices and arrays []interface{}, and maps with string
+// keys *Tree, with their keys sorted. Strings, booleans, time.Time,
+// LocalDateTime, LocalDate and LocalTime are kept, and a *Tree is copied.
+func (t *Tree) Set(path string, value interface{}) error {
+	parts, err := splitKeyPath(path)
+	if err != nil {
+		return err
+	}
+	cur := t
+	for _, part := range parts[:len(parts)-1] {
+		e := cur.entries[part]
+		if e == nil {
+			sub := newTree(cur.keyPath(part))
+			cur.set(part, sub)
+			cur = sub
+			continue
+		}
+		sub, ok := e.value.(*Tree)
+		if !ok {
+			return e.typeError(cur.keyPath(part), "a table")
+		}
+		cur = sub
+	}
+	key := parts[len(parts)-1]
+	v, err := toTreeValue(cur.keyPath(key), value)
+	if err != nil {
+		return err
+	}
+	cur.set(key, v)
+	return nil
+}
+
+// set sets the value of key, keeping the order in which keys were defined.
+func (t *Tree) set(key string, v interface{}) {
+	if _, ok := t.entries[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.entries[key] = &treeEntry{value: v}
+}
+
+// Delete removes the key path and its value.
+func (t *Tree) Delete(path string) error {
+	parent, key, _, err := t.lookup(path, true)
+	if err != nil {
+		return err
+	}
+	delete(parent.entries, key)
+	for i, k := range parent.keys {
+		if k == key {
+			parent.keys = append(parent.keys[:i], parent.keys[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// toTreeValue converts the Go value v to a value of a Tree at the key path.
+func toTreeValue(path []string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string, int64, float64, bool, time.Time, LocalDateTime, LocalDate, LocalTime:
+		return v, nil
+	case *Tree:
+		if v == nil {
+			break
+		}
+		return v.copyTo(path), nil
+	}
+	rv := indirectValue(reflect.ValueOf(v))
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("toml: %s: cannot set nil", formatKey(path))
+	}
+	if isDateTimeType(rv.Type()) {
+		return rv.Interface(), nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("toml: %s: integer %d overflows a TOML integer", formatKey(path), u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Slice, reflect.Array:
+		a := make([]interface{}, rv.Len())
+		for i := range a {
+			var err error
+			if a[i], err = toTreeValue(path, rv.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			break
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		tree := newTree(path)
+		for _, k := range keys {
+			ev, err := toTreeValue(append(path[:len(path):len(path)], k.String()), rv.MapIndex(k).Interface())
+			if err != nil {
+				return nil, err
+			}
+			tree.set(k.String(), ev)
+		}
+		return tree, nil
+	}
+	return nil, fmt.Errorf("toml: %s: cannot set %s", formatKey(path), describeValue(rv))
+}
+
+// copyTo returns a deep copy of t at the key path, so that the copy can be
+// set in a Tree while t stays at its own path.
+func (t *Tree) copyTo(path []string) *Tree {
+	c := newTree(path)
+	c.keys = append([]string(nil), t.keys...)
+	for key, e := range t.entries {
+		ce := *e
+		ce.value = copyValue(e.value, append(path[:len(path):len(path)], key))
+		c.entries[key] = &ce
+	}
+	return c
+}
+
+func copyValue(v interface{}, path []string) interface{} {
+	switch v := v.(type) {
+	case *Tree:
+		return v.copyTo(path)
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = copyValue(e, path)
+		}
+		return a
+	}
+	return v
+}
+
+// typeError returns an error for the value of e at the key path, which is
+// not of the type described by want.
+func (e *treeEntry) typeError(path []string, want string) error {
+	msg := fmt.Sprintf("%s is %s, not %s", formatKey(path), describeTreeValue(e.value), want)
+	if e.line == 0 {
+		return fmt.Errorf("toml: %s", msg)
+	}
+	return fmt.Errorf("toml: line %d, column %d: %s", e.line, column(e.src, e.pos), msg)
+}
+
+// describeTreeValue returns the TOML type of a value of a Tree for error
+// messages.
+func describeTreeValue(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64:
+		return "an integer"
+	case float64:
+		return "a float"
+	case bool:
+		return "a boolean"
+	case time.Time:
+		return "an offset date-time"
+	case LocalDateTime:
+		return "a local date-time"
+	case LocalDate:
+		return "a local date"
+	case LocalTime:
+		return "a local time"
+	case []interface{}:
+		return "an array"
+	case *Tree:
+		return "a table"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// GetString returns the string of the key path.
+func (t *Tree) GetString(path string) (string, error) {
+	return t.getString(path, "", true)
+}
+
+// GetStringDefault returns the string of the key path, or def if the key
+// is not defined.
+func (t *Tree) GetStringDefault(path, def string) (string, error) {
+	return t.getString(path, def, false)
+}
+
+func (t *Tree) getString(path, def string, required bool) (string, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	v, ok := e.value.(string)
+	if !ok {
+		return def, e.typeError(parent.keyPath(key), "a string")
+	}
+	return v, nil
+}
+
+// GetInt64 returns the integer of the key path.
+func (t *Tree) GetInt64(path string) (int64, error) {
+	return t.getInt64(path, 0, true)
+}
+
+// GetInt64Default returns the integer of the key path, or def if the key
+// is not defined.
+func (t *Tree) GetInt64Default(path string, def int64) (int64, error) {
+	return t.getInt64(path, def, false)
+}
+
+func (t *Tree) getInt64(path string, def int64, required bool) (int64, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	v, ok := e.value.(int64)
+	if !ok {
+		return def, e.typeError(parent.keyPath(key), "an integer")
+	}
+	return v, nil
+}
+
+// GetFloat64 returns the float or integer of the key path.
+func (t *Tree) GetFloat64(path string) (float64, error) {
+	return t.getFloat64(path, 0, true)
+}
+
+// GetFloat64Default returns the float or integer of the key path, or def if
+// the key is not defined.
+func (t *Tree) GetFloat64Default(path string, def float64) (float64, error) {
+	return t.getFloat64(path, def, false)
+}
+
+func (t *Tree) getFloat64(path string, def float64, required bool) (float64, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	switch v := e.value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	}
+	return def, e.typeError(parent.keyPath(key), "a float")
+}
+
+// GetBool returns the boolean of the key path.
+func (t *Tree) GetBool(path string) (bool, error) {
+	return t.getBool(path, false, true)
+}
+
+// GetBoolDefault returns the boolean of the key path, or def if the key is
+// not defined.
+func (t *Tree) GetBoolDefault(path string, def bool) (bool, error) {
+	return t.getBool(path, def, false)
+}
+
+func (t *Tree) getBool(path string, def bool, required bool) (bool, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	v, ok := e.value.(bool)
+	if !ok {
+		return def, e.typeError(parent.keyPath(key), "a boolean")
+	}
+	return v, nil
+}
+
+// GetTime returns the date-time of the key path. Like Unmarshal into a
+// time.Time, it returns local date-times, local dates and local times in
+// time.Local.
+func (t *Tree) GetTime(path string) (time.Time, error) {
+	return t.getTime(path, time.Time{}, true)
+}
+
+// GetTimeDefault returns the date-time of the key path, or def if the key
+// is not defined.
+func (t *Tree) GetTimeDefault(path string, def time.Time) (time.Time, error) {
+	return t.getTime(path, def, false)
+}
+
+func (t *Tree) getTime(path string, def time.Time, required bool) (time.Time, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	switch v := e.value.(type) {
+	case time.Time:
+		return v, nil
+	case LocalDateTime:
+		return v.In(time.Local), nil
+	case LocalDate:
+		return v.In(time.Local), nil
+	case LocalTime:
+		return v.In(time.Local), nil
+	}
+	return def, e.typeError(parent.keyPath(key), "a date-time")
+}
+
+// GetArray returns the array or array of tables of the key path.
+func (t *Tree) GetArray(path string) ([]interface{}, error) {
+	return t.getArray(path, nil, true)
+}
+
+// GetArrayDefault returns the array or array of tables of the key path, or
+// def if the key is not defined.
+func (t *Tree) GetArrayDefault(path string, def []interface{}) ([]interface{}, error) {
+	return t.getArray(path, def, false)
+}
+
+func (t *Tree) getArray(path string, def []interface{}, required bool) ([]interface{}, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	v, ok := e.value.([]interface{})
+	if !ok {
+		return def, e.typeError(parent.keyPath(key), "an array")
+	}
+	return v, nil
+}
+
+// GetTree returns the table of the key path.
+func (t *Tree) GetTree(path string) (*Tree, error) {
+	return t.getTree(path, nil, true)
+}
+
+// GetTreeDefault returns the table of the key path, or def if the key is
+// not defined.
+func (t *Tree) GetTreeDefault(path string, def *Tree) (*Tree, error) {
+	return t.getTree(path, def, false)
+}
+
+func (t *Tree) getTree(path string, def *Tree, required bool) (*Tree, error) {
+	parent, key, e, err := t.lookup(path, required)
+	if e == nil {
+		return def, err
+	}
+	v, ok := e.value.(*Tree)
+	if !ok {
+		return def, e.typeError(parent.keyPath(key), "a table")
+	}
+	return v, nil
+}
+
+// keyPath returns the key path of key in t.
+func (t *Tree) keyPath(key string) []string {
+	return append(t.path[:len(t.path):len(t.path)], key)
+}
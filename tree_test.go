@@ -0,0 +1,263 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+const treeInput = `title = "example"
+ratio = 0.5
+retries = 3
+enabled = true
+released = 1979-05-27T07:32:00Z
+day = 1979-05-27
+
+[server]
+host = "localhost"
+tls = { cert = "cert.pem", ports = [443, 8443] }
+
+[[plugins]]
+name = "a"
+[[plugins]]
+name = "b"
+`
+
+func TestTree(t *testing.T) {
+	tree, err := ParseTree("", treeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tree.Keys(), []string{"title", "ratio", "retries", "enabled", "released", "day", "server", "plugins"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys: got %q, expected %q", got, want)
+	}
+	if got := tree.Get("server.tls.cert"); got != "cert.pem" {
+		t.Errorf("server.tls.cert: got %v, expected cert.pem", got)
+	}
+	if got := tree.Get(`"server".tls.ports`); !reflect.DeepEqual(got, []interface{}{int64(443), int64(8443)}) {
+		t.Errorf("server.tls.ports: got %#v, expected [443 8443]", got)
+	}
+	if got := tree.Get("day"); got != (LocalDate{1979, time.May, 27}) {
+		t.Errorf("day: got %#v, expected 1979-05-27", got)
+	}
+	for path, want := range map[string]bool{"title": true, "server.tls": true, "server.port": false, "title.x": false, "a..b": false} {
+		if got := tree.Has(path); got != want {
+			t.Errorf("Has(%q) = %v, expected %v", path, got, want)
+		}
+	}
+	if got := tree.Get("server.port"); got != nil {
+		t.Errorf("server.port: got %v, expected nil", got)
+	}
+
+	check := func(path string, got, want interface{}, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %#v, expected %#v", path, got, want)
+		}
+	}
+	s, err := tree.GetString("server.host")
+	check("server.host", s, "localhost", err)
+	i, err := tree.GetInt64("retries")
+	check("retries", i, int64(3), err)
+	f, err := tree.GetFloat64("ratio")
+	check("ratio", f, 0.5, err)
+	f, err = tree.GetFloat64("retries")
+	check("retries", f, 3.0, err)
+	b, err := tree.GetBool("enabled")
+	check("enabled", b, true, err)
+	tm, err := tree.GetTime("released")
+	check("released", tm, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC), err)
+	tm, err = tree.GetTime("day")
+	check("day", tm, time.Date(1979, 5, 27, 0, 0, 0, 0, time.Local), err)
+	a, err := tree.GetArray("server.tls.ports")
+	check("server.tls.ports", a, []interface{}{int64(443), int64(8443)}, err)
+
+	plugins, err := tree.GetArray("plugins")
+	if err != nil || len(plugins) != 2 {
+		t.Fatalf("plugins: got %v, %v, expected 2 tables", plugins, err)
+	}
+	s, err = plugins[1].(*Tree).GetString("name")
+	check("plugins.name", s, "b", err)
+
+	tls, err := tree.GetTree("server.tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err = tls.GetString("cert")
+	check("cert", s, "cert.pem", err)
+	_, err = tls.GetInt64("cert")
+	if want := "toml: line 10, column 9: server.tls.cert is a string, not an integer"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+}
+
+func TestTreeDefault(t *testing.T) {
+	tree, err := ParseTree("", treeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := tree.GetStringDefault("server.host", "x")
+	if s != "localhost" || err != nil {
+		t.Errorf("server.host: got %q, %v, expected localhost", s, err)
+	}
+	s, err = tree.GetStringDefault("server.user", "root")
+	if s != "root" || err != nil {
+		t.Errorf("server.user: got %q, %v, expected root", s, err)
+	}
+	i, err := tree.GetInt64Default("server.port", 80)
+	if i != 80 || err != nil {
+		t.Errorf("server.port: got %d, %v, expected 80", i, err)
+	}
+	f, err := tree.GetFloat64Default("missing.ratio", 1.5)
+	if f != 1.5 || err != nil {
+		t.Errorf("missing.ratio: got %v, %v, expected 1.5", f, err)
+	}
+	b, err := tree.GetBoolDefault("debug", true)
+	if !b || err != nil {
+		t.Errorf("debug: got %v, %v, expected true", b, err)
+	}
+	def := time.Unix(0, 0)
+	tm, err := tree.GetTimeDefault("updated", def)
+	if !tm.Equal(def) || err != nil {
+		t.Errorf("updated: got %v, %v, expected %v", tm, err, def)
+	}
+	a, err := tree.GetArrayDefault("tags", []interface{}{"x"})
+	if !reflect.DeepEqual(a, []interface{}{"x"}) || err != nil {
+		t.Errorf("tags: got %v, %v, expected [x]", a, err)
+	}
+	defTree := NewTree()
+	sub, err := tree.GetTreeDefault("client", defTree)
+	if sub != defTree || err != nil {
+		t.Errorf("client: got %v, %v, expected the default", sub, err)
+	}
+	i, err = tree.GetInt64Default("title", 1)
+	if want := "toml: line 1, column 1: title is a string, not an integer"; i != 1 || err == nil || err.Error() != want {
+		t.Errorf("title: got %d, %v, expected 1, %s", i, err, want)
+	}
+}
+
+func TestTreeError(t *testing.T) {
+	tree, err := ParseTree("", treeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range []struct {
+		name string
+		get  func() error
+		want string
+	}{
+		{"string", func() error { _, err := tree.GetString("retries"); return err }, "toml: line 3, column 1: retries is an integer, not a string"},
+		{"int64", func() error { _, err := tree.GetInt64("ratio"); return err }, "toml: line 2, column 1: ratio is a float, not an integer"},
+		{"float64", func() error { _, err := tree.GetFloat64("enabled"); return err }, "toml: line 4, column 1: enabled is a boolean, not a float"},
+		{"bool", func() error { _, err := tree.GetBool("title"); return err }, "toml: line 1, column 1: title is a string, not a boolean"},
+		{"time", func() error { _, err := tree.GetTime("server"); return err }, "toml: line 8, column 1: server is a table, not a date-time"},
+		{"array", func() error { _, err := tree.GetArray("day"); return err }, "toml: line 6, column 1: day is a local date, not an array"},
+		{"tree", func() error { _, err := tree.GetTree("plugins"); return err }, "toml: line 12, column 1: plugins is an array, not a table"},
+		{"parent", func() error { _, err := tree.GetString("title.x"); return err }, "toml: line 1, column 1: title is a string, not a table"},
+		{"missing", func() error { _, err := tree.GetString("server.user"); return err }, "toml: key server.user is not defined"},
+		{"missing parent", func() error { _, err := tree.GetBool("a.b.c"); return err }, "toml: key a.b.c is not defined"},
+		{"invalid path", func() error { _, err := tree.GetString("a..b"); return err }, `toml: invalid key path "a..b"`},
+		{"delete missing", func() error { return tree.Delete("server.user") }, "toml: key server.user is not defined"},
+		{"set through value", func() error { return tree.Set("server.host.name", "x") }, "toml: line 9, column 1: server.host is a string, not a table"},
+		{"set nil", func() error { return tree.Set("server.x", nil) }, "toml: server.x: cannot set nil"},
+		{"set func", func() error { return tree.Set("x", func() {}) }, "toml: x: cannot set Go value of type func()"},
+		{"set uint64", func() error { return tree.Set("x", uint64(1<<63)) }, "toml: x: integer 9223372036854775808 overflows a TOML integer"},
+	} {
+		if err := test.get(); err == nil || err.Error() != test.want {
+			t.Errorf("%s: got error %v, expected %s", test.name, err, test.want)
+		}
+	}
+}
+
+func TestTreeEdit(t *testing.T) {
+	tree, err := ParseTree("", treeInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("server.port", uint16(8080)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("server.tls.ports", []int{443}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set(`client."user agent"`, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("limits", map[string]interface{}{"b": 2.5, "a": []string{"x"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("server.host"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete("plugins"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := tree.Keys(), []string{"title", "ratio", "retries", "enabled", "released", "day", "server", "client", "limits"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys: got %q, expected %q", got, want)
+	}
+	server, err := tree.GetTree("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := server.Keys(), []string{"tls", "port"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("server keys: got %q, expected %q", got, want)
+	}
+	if got := tree.Get("server.port"); got != int64(8080) {
+		t.Errorf("server.port: got %#v, expected 8080", got)
+	}
+	if got := tree.Get("server.tls.ports"); !reflect.DeepEqual(got, []interface{}{int64(443)}) {
+		t.Errorf("server.tls.ports: got %#v, expected [443]", got)
+	}
+	if got := tree.Get(`client."user agent"`); got != "x" {
+		t.Errorf("client.\"user agent\": got %#v, expected x", got)
+	}
+	limits, err := tree.GetTree("limits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := limits.Keys(), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("limits keys: got %q, expected %q", got, want)
+	}
+	_, err = limits.GetBool("b")
+	if want := "toml: limits.b is a float, not a boolean"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+
+	moved := NewTree()
+	if err := moved.Set("x", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("a.b", moved); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tree.GetString("a.b.x")
+	if want := "toml: a.b.x is a boolean, not a string"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+	_, err = moved.GetString("x")
+	if want := "toml: x is a boolean, not a string"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+
+	// A table set at another key is a copy, the original keeps its path.
+	if err := tree.Set("copy", server); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Set("copy.tls.extra", 1); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Has("server.tls.extra") {
+		t.Errorf("server.tls.extra: set through the copy")
+	}
+	_, err = tree.GetInt64("server.tls.cert")
+	if want := "toml: line 10, column 9: server.tls.cert is a string, not an integer"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+	_, err = tree.GetInt64("copy.tls.cert")
+	if want := "toml: line 10, column 9: copy.tls.cert is a string, not an integer"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, expected %s", err, want)
+	}
+}